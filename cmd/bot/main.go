@@ -1,76 +1,223 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/netip"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/centromex/grocery-bot/internal/bot"
 	"github.com/centromex/grocery-bot/internal/db"
+	"github.com/centromex/grocery-bot/internal/logging"
 	"github.com/centromex/grocery-bot/internal/translator"
 )
 
+const defaultDBPath = "./data/centromex.db"
+
 func main() {
-	log.Println("Starting Centromex Grocery Bot...")
+	migrateOnly := flag.Bool("migrate-only", false, "apply pending database migrations and exit")
+	dryRun := flag.Bool("dry-run", false, "run pending migrations in a rolled-back transaction and exit")
+	flag.Parse()
+
+	// Family text is never logged unless LOG_DEBUG is explicitly set
+	logging.Setup(os.Stderr, logging.Options{
+		Debug: getEnvBool("LOG_DEBUG"),
+		JSON:  os.Getenv("LOG_FORMAT") == "json",
+	})
+
+	if *migrateOnly || *dryRun {
+		runMigrations(getEnvOrDefault("DB_PATH", defaultDBPath), *dryRun)
+		return
+	}
+
+	slog.Info("Starting Centromex Grocery Bot...")
 
 	// Load configuration from environment
 	config := loadConfig()
 
+	if err := run(config); err != nil {
+		fatal("Bot error", "err", err)
+	}
+	slog.Info("Shut down cleanly")
+}
+
+// run starts the bot and its background jobs and blocks until SIGINT or
+// SIGTERM, then waits for them to stop before the database is closed.
+func run(config Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize database
-	log.Println("Initializing database...")
+	slog.Info("Initializing database...")
 	database, err := db.New(config.DBPath, config.DBKey)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer database.Close()
 
 	// Initialize translator
-	log.Println("Initializing translator...")
-	trans, err := translator.New(translator.Config{
-		ModelPath:   config.ModelPath,
-		ContextSize: 2048,
-		Threads:     4,
-		OpenAIKey:   config.OpenAIKey,
-	})
+	slog.Info("Initializing translator...")
+	trans, err := newTranslator(config)
 	if err != nil {
-		log.Fatalf("Failed to initialize translator: %v", err)
+		return fmt.Errorf("failed to initialize translator: %w", err)
 	}
 	defer trans.Close()
 
 	// Initialize bot
-	log.Println("Starting Telegram bot...")
+	slog.Info("Starting Telegram bot...")
 	telegramBot, err := bot.New(bot.Config{
 		Token:          config.TelegramToken,
 		VolunteerChat:  config.VolunteerChat,
 		CoordinatorIDs: config.CoordinatorIDs,
 		WebhookURL:     config.WebhookURL,
 		WebhookSecret:  config.WebhookSecret,
+
+		CancelNeedsApproval: config.CancelNeedsApproval,
+		ReviewBeforePosting: config.ReviewBeforePosting,
+		Workers:             config.Workers,
+
+		WebhookAllowedIPs:     config.WebhookAllowedIPs,
+		WebhookClientIPHeader: config.WebhookClientIPHeader,
 	}, database, trans)
 	if err != nil {
-		log.Fatalf("Failed to initialize bot: %v", err)
+		return fmt.Errorf("failed to initialize bot: %w", err)
 	}
 
+	var jobs sync.WaitGroup
+	jobs.Add(2)
+
 	// Start background cleanup job
 	go func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-		for range ticker.C {
-			purged, err := database.PurgeOldRequests(48 * time.Hour)
+		defer jobs.Done()
+		every(ctx, 1*time.Hour, func() {
+			purged, err := database.PurgeOldRequests(ctx, 48*time.Hour)
 			if err != nil {
-				log.Printf("Error purging old requests: %v", err)
+				slog.Error("Error purging old requests", "err", err)
 			} else if purged > 0 {
-				log.Printf("Purged %d old requests", purged)
+				slog.Info("Purged old requests", "count", purged)
 			}
-		}
+
+			// Abandoned intake conversations hold family contact details
+			purged, err = database.PurgeStaleSessions(ctx, 48*time.Hour)
+			if err != nil {
+				slog.Error("Error purging stale sessions", "err", err)
+			} else if purged > 0 {
+				slog.Info("Purged stale sessions", "count", purged)
+			}
+
+			purged, err = database.PurgeFinishedUpdates(ctx, 48*time.Hour)
+			if err != nil {
+				slog.Error("Error purging finished updates", "err", err)
+			} else if purged > 0 {
+				slog.Info("Purged finished updates", "count", purged)
+			}
+		})
 	}()
 
-	log.Println("Bot is running. Press Ctrl+C to stop.")
+	// Retry translations that failed or were interrupted
+	go func() {
+		defer jobs.Done()
+		every(ctx, 5*time.Minute, func() {
+			telegramBot.RetryTranslations(ctx, 10*time.Minute)
+		})
+	}()
+
+	slog.Info("Bot is running. Press Ctrl+C to stop.")
+
+	// Run the bot (blocks until shutdown, then drains in-flight updates)
+	err = telegramBot.Run(ctx)
+
+	// Stop the jobs too if Run returned on its own
+	stop()
+	jobs.Wait()
+	return err
+}
+
+// every calls fn at each interval until ctx is cancelled
+func every(ctx context.Context, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn()
+		}
+	}
+}
+
+// newTranslator creates the configured translator, backed by the fallback
+// backend or model if one is set
+func newTranslator(config Config) (translator.Translator, error) {
+	primary, err := translator.New(translator.Config{
+		Backend:    config.TranslatorBackend,
+		OpenAIKey:  config.OpenAIKey,
+		BaseURL:    config.TranslatorURL,
+		Model:      config.TranslatorModel,
+		PromptPath: config.PromptPath,
+		Timeout:    config.TranslatorTimeout,
+		MaxRetries: config.TranslatorMaxRetries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.FallbackBackend == "" && config.FallbackModel == "" {
+		return primary, nil
+	}
+
+	// A fallback model alone means the same backend with a different model
+	backend, baseURL := config.FallbackBackend, config.FallbackURL
+	if backend == "" {
+		backend = config.TranslatorBackend
+		if baseURL == "" {
+			baseURL = config.TranslatorURL
+		}
+	}
+	fallback, err := translator.New(translator.Config{
+		Backend:    backend,
+		OpenAIKey:  config.OpenAIKey,
+		BaseURL:    baseURL,
+		Model:      config.FallbackModel,
+		PromptPath: config.PromptPath,
+		Timeout:    config.TranslatorTimeout,
+		MaxRetries: config.TranslatorMaxRetries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Translation fallback configured", "backend", backend, "model", config.FallbackModel)
+	return translator.NewChain(primary, fallback), nil
+}
+
+// runMigrations applies (or, with dryRun, only checks) pending migrations
+func runMigrations(dbPath string, dryRun bool) {
+	slog.Info("Running migrations", "db", dbPath, "dry_run", dryRun)
+
+	applied, err := db.Migrate(dbPath, dryRun)
+	for _, m := range applied {
+		slog.Info("Migration", "version", m.Version, "name", m.Name)
+	}
+	if err != nil {
+		fatal("Migration failed", "err", err)
+	}
 
-	// Run the bot (blocks until shutdown)
-	if err := telegramBot.Run(); err != nil {
-		log.Fatalf("Bot error: %v", err)
+	if len(applied) == 0 {
+		slog.Info("Database is up to date")
+	} else if dryRun {
+		slog.Info("Pending migrations ran cleanly (rolled back)", "count", len(applied))
+	} else {
+		slog.Info("Applied migrations", "count", len(applied))
 	}
 }
 
@@ -80,28 +227,70 @@ type Config struct {
 	CoordinatorIDs []int64
 	DBPath         string
 	DBKey          string
-	ModelPath      string
 	WebhookURL     string
 	WebhookSecret  string
 	OpenAIKey      string
+
+	TranslatorBackend string // "openai" or "local"
+	TranslatorURL     string // Base URL override, e.g. a llama.cpp or Ollama server
+	TranslatorModel   string // Model override
+	PromptPath        string // Translation prompt template, reloaded when it changes
+
+	TranslatorTimeout    time.Duration // Per API call
+	TranslatorMaxRetries int           // On timeouts, rate limits and server errors
+
+	FallbackBackend string // Tried when the translator above fails; optional
+	FallbackURL     string
+	FallbackModel   string
+
+	CancelNeedsApproval bool
+	ReviewBeforePosting bool
+	Workers             int // Updates processed at once
+
+	WebhookAllowedIPs     []netip.Prefix // Empty allows any source
+	WebhookClientIPHeader string         // Set when a reverse proxy terminates connections
 }
 
 func loadConfig() Config {
 	config := Config{
 		TelegramToken: mustGetEnv("TELEGRAM_BOT_TOKEN"),
-		DBPath:        getEnvOrDefault("DB_PATH", "./data/centromex.db"),
+		DBPath:        getEnvOrDefault("DB_PATH", defaultDBPath),
 		DBKey:         mustGetEnv("DB_ENCRYPTION_KEY"),
-		ModelPath:     getEnvOrDefault("MODEL_PATH", "./models/llama-3.2-3b.Q4_K_M.gguf"),
 		WebhookURL:    os.Getenv("WEBHOOK_URL"),    // Optional - if set, uses webhook mode
 		WebhookSecret: os.Getenv("WEBHOOK_SECRET"), // Secret token for webhook verification
 		OpenAIKey:     os.Getenv("OPENAI_API_KEY"), // Optional - for translation
+
+		TranslatorBackend: getEnvOrDefault("TRANSLATOR_BACKEND", translator.BackendOpenAI),
+		TranslatorURL:     os.Getenv("TRANSLATOR_URL"),   // Optional - defaults per backend
+		TranslatorModel:   os.Getenv("TRANSLATOR_MODEL"), // Optional - defaults per backend
+		PromptPath:        getEnvOrDefault("PROMPT_PATH", "./prompts/translate.txt"),
+
+		FallbackBackend: os.Getenv("FALLBACK_TRANSLATOR_BACKEND"), // Optional - e.g. "local"
+		FallbackURL:     os.Getenv("FALLBACK_TRANSLATOR_URL"),     // Optional - defaults per backend
+		FallbackModel:   os.Getenv("FALLBACK_TRANSLATOR_MODEL"),   // Optional - defaults per backend
 	}
 
+	config.TranslatorTimeout = getEnvDuration("TRANSLATOR_TIMEOUT", 30*time.Second)
+	config.TranslatorMaxRetries = getEnvInt("TRANSLATOR_MAX_RETRIES", 3)
+
+	// Whether volunteers' /cancel waits for a coordinator to /release
+	config.CancelNeedsApproval = getEnvBool("CANCEL_REQUIRES_APPROVAL")
+
+	// Whether coordinators check each translated card before it's posted
+	config.ReviewBeforePosting = getEnvBool("REVIEW_BEFORE_POSTING")
+
+	// Updates from different users are processed in parallel
+	config.Workers = getEnvInt("WORKER_COUNT", 4)
+
+	// Optionally only accept webhook requests from Telegram's networks
+	config.WebhookAllowedIPs = parseIPRanges(os.Getenv("WEBHOOK_ALLOWED_IPS"))
+	config.WebhookClientIPHeader = os.Getenv("WEBHOOK_CLIENT_IP_HEADER")
+
 	// Parse volunteer chat ID
 	volunteerChatStr := mustGetEnv("VOLUNTEER_CHAT_ID")
 	volunteerChat, err := strconv.ParseInt(volunteerChatStr, 10, 64)
 	if err != nil {
-		log.Fatalf("Invalid VOLUNTEER_CHAT_ID: %v", err)
+		fatal("Invalid VOLUNTEER_CHAT_ID", "err", err)
 	}
 	config.VolunteerChat = volunteerChat
 
@@ -114,22 +303,56 @@ func loadConfig() Config {
 		}
 		id, err := strconv.ParseInt(idStr, 10, 64)
 		if err != nil {
-			log.Fatalf("Invalid coordinator ID '%s': %v", idStr, err)
+			fatal("Invalid coordinator ID", "value", idStr, "err", err)
 		}
 		config.CoordinatorIDs = append(config.CoordinatorIDs, id)
 	}
 
 	if len(config.CoordinatorIDs) == 0 {
-		log.Fatal("At least one coordinator ID is required")
+		fatal("At least one coordinator ID is required")
 	}
 
 	return config
 }
 
+// parseIPRanges parses comma-separated CIDRs or addresses. "telegram"
+// stands for Telegram's published webhook ranges.
+func parseIPRanges(value string) []netip.Prefix {
+	var ranges []netip.Prefix
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			continue
+		case strings.EqualFold(item, "telegram"):
+			ranges = append(ranges, bot.TelegramIPRanges...)
+		case strings.Contains(item, "/"):
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				fatal("Invalid WEBHOOK_ALLOWED_IPS", "value", item, "err", err)
+			}
+			ranges = append(ranges, prefix.Masked())
+		default:
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				fatal("Invalid WEBHOOK_ALLOWED_IPS", "value", item, "err", err)
+			}
+			ranges = append(ranges, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return ranges
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
 func mustGetEnv(key string) string {
 	value := os.Getenv(key)
 	if value == "" {
-		log.Fatalf("Required environment variable %s is not set", key)
+		fatal("Required environment variable is not set", "key", key)
 	}
 	return value
 }
@@ -140,3 +363,42 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvBool parses an optional true/false variable, defaulting to false
+func getEnvBool(key string) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		fatal("Invalid environment variable", "key", key, "err", err)
+	}
+	return parsed
+}
+
+// getEnvDuration parses an optional duration such as "45s"
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		fatal("Invalid environment variable", "key", key, "value", value)
+	}
+	return parsed
+}
+
+// getEnvInt parses an optional non-negative integer
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		fatal("Invalid environment variable", "key", key, "value", value)
+	}
+	return parsed
+}
@@ -0,0 +1,56 @@
+// Command rekey rotates DB_ENCRYPTION_KEY.
+//
+// Stop the bot, then run with the current key in DB_ENCRYPTION_KEY and the
+// replacement in NEW_DB_ENCRYPTION_KEY. If the rotation is interrupted,
+// run it again with the same two keys and it will pick up where it left off.
+// Afterwards, set DB_ENCRYPTION_KEY to the new key and restart the bot.
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/centromex/grocery-bot/internal/db"
+	"github.com/centromex/grocery-bot/internal/logging"
+)
+
+func main() {
+	logging.Setup(os.Stderr, logging.Options{JSON: os.Getenv("LOG_FORMAT") == "json"})
+
+	dbPath := getEnvOrDefault("DB_PATH", "./data/centromex.db")
+	oldKey := mustGetEnv("DB_ENCRYPTION_KEY")
+	newKey := mustGetEnv("NEW_DB_ENCRYPTION_KEY")
+
+	slog.Info("Rotating encryption key...", "db_path", dbPath)
+
+	result, err := db.Rekey(dbPath, oldKey, newKey)
+	if err != nil {
+		fatal("Key rotation failed", "err", err)
+	}
+
+	if result.Resumed {
+		slog.Info("Resumed unfinished rotation")
+	}
+	slog.Info("Re-encrypted rows", "rows", result.Rows, "from_version", result.FromVersion, "to_version", result.ToVersion)
+	slog.Info("Done. Set DB_ENCRYPTION_KEY to the new key and restart the bot.")
+}
+
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+func mustGetEnv(key string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		fatal("Required environment variable is not set", "key", key)
+	}
+	return value
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
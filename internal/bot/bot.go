@@ -1,49 +1,83 @@
 package bot
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
+	"log/slog"
+	"net/netip"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"github.com/centromex/grocery-bot/internal/db"
+	"github.com/centromex/grocery-bot/internal/models"
 	"github.com/centromex/grocery-bot/internal/translator"
 )
 
 type Bot struct {
-	api            *tgbotapi.BotAPI
-	db             *db.DB
-	translator     *translator.Translator
-	volunteerChat  int64 // Telegram chat ID for volunteer group
-	coordinatorIDs []int64
-	webhookURL     string
-	webhookSecret  string
-	processedIDs   map[int]bool // Track processed update IDs to prevent duplicates
-	processMutex   sync.Mutex   // Protects processedIDs map
+	api                 *tgbotapi.BotAPI
+	db                  *db.DB
+	translator          translator.Translator
+	volunteerChat       int64 // Telegram chat ID for volunteer group
+	coordinatorIDs      []int64
+	webhookURL          string
+	webhookSecret       string
+	cancelNeedsApproval bool            // If set, /cancel asks coordinators instead of releasing
+	reviewBeforePosting bool            // If set, coordinators approve new requests before they're posted
+	inFlight            map[string]bool // Button presses being handled, to ignore double taps
+	inFlightMutex       sync.Mutex      // Protects inFlight map
+	requestLocks        requestLocks    // Serializes changes to each request
+
+	webhookAllowedIPs     []netip.Prefix // Empty means any source may POST updates
+	webhookClientIPHeader string         // Set when behind a reverse proxy
+
+	queues   []chan tgbotapi.Update // One per worker; each user always goes to the same one
+	workers  sync.WaitGroup         // Goroutines processing queues, drained on shutdown
+	stopping chan struct{}          // Closed on shutdown; the queues never are, as handlers may still send
+
+	promoted          map[int64]bool // Coordinators added with /promote, mirrored from the database
+	coordinatorsMutex sync.RWMutex   // Protects promoted map
 }
 
 type Config struct {
-	Token          string
-	VolunteerChat  int64
-	CoordinatorIDs []int64
-	WebhookURL     string // If set, use webhook mode; otherwise use polling
-	WebhookSecret  string // Secret token for webhook verification
+	Token               string
+	VolunteerChat       int64
+	CoordinatorIDs      []int64
+	WebhookURL          string // If set, use webhook mode; otherwise use polling
+	WebhookSecret       string // Secret token Telegram sends with each update; required for webhooks
+	CancelNeedsApproval bool   // If set, a coordinator must /release a volunteer's /cancel
+	ReviewBeforePosting bool   // If set, new requests are DM'd to their coordinator for approval first
+	Workers             int    // Updates processed at once; defaults to 4
+
+	WebhookAllowedIPs     []netip.Prefix // Optional; only accept webhook requests from these networks
+	WebhookClientIPHeader string         // Header a reverse proxy puts the client IP in, e.g. X-Forwarded-For
 }
 
-func New(cfg Config, database *db.DB, trans *translator.Translator) (*Bot, error) {
+func New(cfg Config, database *db.DB, trans translator.Translator) (*Bot, error) {
+	if cfg.WebhookURL != "" && !validSecretToken.MatchString(cfg.WebhookSecret) {
+		return nil, fmt.Errorf("webhook mode needs a secret of 1-256 letters, digits, _ or -")
+	}
+
 	api, err := tgbotapi.NewBotAPI(cfg.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
 
-	log.Printf("Authorized on account %s", api.Self.UserName)
+	slog.Info("Authorized", "account", api.Self.UserName)
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	queues := make([]chan tgbotapi.Update, workers)
+	for i := range queues {
+		queues[i] = make(chan tgbotapi.Update, queueSize)
+	}
 
 	return &Bot{
 		api:            api,
@@ -53,81 +87,88 @@ func New(cfg Config, database *db.DB, trans *translator.Translator) (*Bot, error
 		coordinatorIDs: cfg.CoordinatorIDs,
 		webhookURL:     cfg.WebhookURL,
 		webhookSecret:  cfg.WebhookSecret,
-		processedIDs:   make(map[int]bool),
+		inFlight:       make(map[string]bool),
+		queues:         queues,
+		stopping:       make(chan struct{}),
+
+		cancelNeedsApproval: cfg.CancelNeedsApproval,
+		reviewBeforePosting: cfg.ReviewBeforePosting,
+
+		webhookAllowedIPs:     cfg.WebhookAllowedIPs,
+		webhookClientIPHeader: cfg.WebhookClientIPHeader,
 	}, nil
 }
 
-// Run starts the bot in either webhook or polling mode
-func (b *Bot) Run() error {
-	if b.webhookURL != "" {
-		return b.runWebhook()
-	}
-	return b.runPolling()
-}
+// shutdownTimeout bounds how long Run waits, once stopped, for the webhook
+// server to close and for updates being processed to finish
+const shutdownTimeout = 20 * time.Second
 
-// runWebhook starts an HTTP server for Telegram webhook updates
-func (b *Bot) runWebhook() error {
-	// Set up the webhook with Telegram
-	webhookConfig, err := tgbotapi.NewWebhook(b.webhookURL + "/webhook")
-	if err != nil {
-		return fmt.Errorf("failed to create webhook config: %w", err)
+// Run starts the bot in either webhook or polling mode and blocks until ctx
+// is cancelled. Updates already queued keep being processed so a deploy
+// doesn't cut off a claim halfway; they are cancelled only if they are
+// still running after shutdownTimeout, and replayed at the next start.
+func (b *Bot) Run(ctx context.Context) error {
+	if err := b.loadCoordinators(ctx); err != nil {
+		return err
 	}
 
-	// Note: SecretToken requires newer telegram-bot-api version
-	// For now, security relies on validating Telegram update format
-
-	_, err = b.api.Request(webhookConfig)
-	if err != nil {
-		return fmt.Errorf("failed to set webhook: %w", err)
-	}
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
 
-	log.Printf("Webhook set to %s/webhook", b.webhookURL)
+	b.startWorkers(workCtx)
+	b.replayPending(workCtx)
 
-	// Set up HTTP handlers
-	http.HandleFunc("/webhook", b.handleWebhook)
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
+	var err error
+	if b.webhookURL != "" {
+		err = b.runWebhook(ctx)
+	} else {
+		err = b.runPolling(ctx, workCtx)
+	}
 
-	log.Println("Starting webhook server on :8080")
-	return http.ListenAndServe(":8080", nil)
+	b.stopWorkers()
+	b.drain(cancelWork)
+	return err
 }
 
-// handleWebhook processes incoming Telegram updates via HTTP
-func (b *Bot) handleWebhook(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// cancelTimeout bounds how long drain waits, after cancelling in-flight
+// updates, for their handlers to return
+const cancelTimeout = 5 * time.Second
+
+// drain waits for queued updates, cancelling them after shutdownTimeout.
+// It returns once the workers have exited, so the database isn't closed
+// under them.
+func (b *Bot) drain(cancelWork context.CancelFunc) {
+	done := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("In-flight updates finished")
 		return
+	case <-time.After(shutdownTimeout):
+		slog.Warn("Timed out waiting for in-flight updates, cancelling them")
+		cancelWork()
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Printf("Error reading webhook body: %v", err)
-		http.Error(w, "Bad request", http.StatusBadRequest)
-		return
-	}
-
-	var update tgbotapi.Update
-	if err := json.Unmarshal(body, &update); err != nil {
-		log.Printf("Error parsing webhook update: %v", err)
-		http.Error(w, "Bad request", http.StatusBadRequest)
-		return
+	select {
+	case <-done:
+		slog.Info("Cancelled updates stopped")
+	case <-time.After(cancelTimeout):
+		slog.Error("Updates still running after being cancelled")
 	}
-
-	// Respond immediately to avoid Telegram timeout
-	w.WriteHeader(http.StatusOK)
-
-	// Process the update asynchronously (especially important for slow LLM translations)
-	go b.processUpdate(update)
 }
 
-// runPolling uses long polling for updates (for local development)
-func (b *Bot) runPolling() error {
+// runPolling uses long polling for updates (for local development) until
+// ctx is cancelled. Updates are queued with workCtx so one just received
+// is still saved.
+func (b *Bot) runPolling(ctx, workCtx context.Context) error {
 	// Remove any existing webhook
 	_, err := b.api.Request(tgbotapi.DeleteWebhookConfig{})
 	if err != nil {
-		log.Printf("Warning: could not remove webhook: %v", err)
+		slog.Warn("Could not remove webhook", "err", err)
 	}
 
 	u := tgbotapi.NewUpdate(0)
@@ -135,61 +176,66 @@ func (b *Bot) runPolling() error {
 
 	updates := b.api.GetUpdatesChan(u)
 
-	log.Println("Starting polling mode")
+	slog.Info("Starting polling mode")
 
-	for update := range updates {
-		b.processUpdate(update)
+	for {
+		select {
+		case <-ctx.Done():
+			b.api.StopReceivingUpdates()
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			// Telegram won't resend it, so there's nothing more to do
+			if err := b.enqueue(workCtx, update); err != nil {
+				slog.Error("Dropping update", "update_id", update.UpdateID, "err", err)
+			}
+		}
 	}
-
-	return nil
 }
 
 // processUpdate handles a single Telegram update
-func (b *Bot) processUpdate(update tgbotapi.Update) {
-	if update.Message == nil {
+func (b *Bot) processUpdate(ctx context.Context, update tgbotapi.Update) {
+	if update.Message == nil && update.CallbackQuery == nil {
 		return
 	}
 
-	// Prevent duplicate processing (Telegram may retry on timeout)
-	b.processMutex.Lock()
-	if b.processedIDs[update.UpdateID] {
-		b.processMutex.Unlock()
-		log.Printf("Skipping duplicate update %d", update.UpdateID)
+	// Inline keyboard button presses
+	if update.CallbackQuery != nil {
+		b.handleCallback(ctx, update.CallbackQuery)
 		return
 	}
-	b.processedIDs[update.UpdateID] = true
-
-	// Clean up old IDs periodically (keep last 1000)
-	if len(b.processedIDs) > 1000 {
-		// Simple cleanup: remove all but keep tracking active
-		b.processedIDs = make(map[int]bool)
-		b.processedIDs[update.UpdateID] = true
-	}
-	b.processMutex.Unlock()
 
 	// Check for new members joining the group
 	if update.Message.NewChatMembers != nil {
-		b.handleNewMembers(update.Message)
+		b.handleNewMembers(ctx, update.Message)
 		return
 	}
 
 	if update.Message.IsCommand() {
-		b.handleCommand(update.Message)
+		b.handleCommand(ctx, update.Message)
 	} else {
-		b.handleMessage(update.Message)
+		b.handleMessage(ctx, update.Message)
 	}
 }
 
-func (b *Bot) handleCommand(msg *tgbotapi.Message) {
+func (b *Bot) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 	userID := msg.From.ID
 
 	switch msg.Command() {
 	case "start":
+		// Families messaging the bot privately get the Spanish intake instead
+		if msg.Chat.IsPrivate() && b.isFamily(ctx, userID) {
+			b.startIntake(ctx, msg.Chat.ID)
+			return
+		}
 		b.sendMessage(msg.Chat.ID, "Welcome to Centromex Grocery Coordination Bot!\n\n"+
 			"Commands:\n"+
 			"/list - See open requests\n"+
 			"/claim <id> - Claim a request\n"+
 			"/mine - See your claimed requests\n"+
+			"/shopping <id> - Let us know you're at the store\n"+
 			"/done <id> - Mark a request as delivered\n"+
 			"/cancel <id> - Cancel your claim\n"+
 			"/help - Show this help message")
@@ -199,52 +245,133 @@ func (b *Bot) handleCommand(msg *tgbotapi.Message) {
 			"/list - See open requests\n"+
 			"/claim <id> - Claim a request\n"+
 			"/mine - See your claimed requests\n"+
+			"/shopping <id> - Let us know you're at the store\n"+
+			"/checklist <id> - Check items off while you shop\n"+
+			"/sub <id> <item> -> <replacement> - Ask the family about a substitution\n"+
+			"/tell <id> <message> - Send the family a message in Spanish\n"+
 			"/done <id> - Mark a request as delivered\n"+
 			"/cancel <id> - Cancel your claim\n\n"+
 			"Coordinators:\n"+
 			"/new <text> - Create a new request\n"+
-			"/status - See all request statuses")
+			"/release <id> - Release a volunteer's claim\n"+
+			"/cancelrequest <id> <reason> - Cancel a request entirely\n"+
+			"/address <id> <address> - Set a delivery address\n"+
+			"/phone <id> <number> - Set the family's phone\n"+
+			"/linkfamily <id> <telegram id> - Send /tell messages straight to the family\n"+
+			"/status - See all request statuses\n"+
+			"/coordinators - List coordinators\n"+
+			"/promote <telegram id> - Make someone a coordinator\n"+
+			"/demote <telegram id> - Remove a coordinator\n\n"+
+			"Managing volunteers:\n"+
+			"/volunteers - List volunteers, approved or not, with notes\n"+
+			"/approve <telegram id> - Let a volunteer claim requests\n"+
+			"/revoke <telegram id> - Remove approval and release their claims\n"+
+			"/ban <telegram id> [reason] - Revoke and remove from the volunteer chat\n"+
+			"/unban <telegram id> - Lift a ban\n"+
+			"/note <telegram id> <notes> - Save notes, e.g. has car, speaks Spanish")
 
 	case "list":
-		b.handleList(msg)
+		b.handleList(ctx, msg)
 
 	case "claim":
-		b.handleClaim(msg, userID)
+		b.handleClaim(ctx, msg, userID)
 
 	case "mine":
-		b.handleMine(msg, userID)
+		b.handleMine(ctx, msg, userID)
+
+	case "shopping":
+		b.handleShopping(ctx, msg, userID)
 
 	case "done":
-		b.handleDone(msg, userID)
+		b.handleDone(ctx, msg, userID)
 
 	case "cancel":
-		b.handleCancel(msg, userID)
+		b.handleCancel(ctx, msg, userID)
+
+	case "release":
+		b.handleRelease(ctx, msg, userID)
+
+	case "cancelrequest":
+		b.handleCancelRequest(ctx, msg, userID)
 
 	case "new":
-		b.handleNew(msg, userID)
+		b.handleNew(ctx, msg, userID)
 
 	case "status":
-		b.handleStatus(msg, userID)
+		b.handleStatus(ctx, msg, userID)
 
 	case "approve":
-		b.handleApprove(msg, userID)
+		b.handleApprove(ctx, msg, userID)
 
 	case "address":
-		b.handleAddress(msg, userID)
+		b.handleAddress(ctx, msg, userID)
+
+	case "phone":
+		b.handlePhone(ctx, msg, userID)
 
 	case "view":
-		b.handleView(msg, userID)
+		b.handleView(ctx, msg, userID)
+
+	case "checklist":
+		b.handleChecklist(ctx, msg, userID)
+
+	case "sub":
+		b.handleSub(ctx, msg, userID)
+
+	case "tell":
+		b.handleTell(ctx, msg, userID)
+
+	case "cancelar":
+		b.cancelIntake(ctx, msg.Chat.ID)
+
+	case "linkfamily":
+		b.handleLinkFamily(ctx, msg, userID)
+
+	case "promote":
+		b.handlePromote(ctx, msg, userID)
+
+	case "demote":
+		b.handleDemote(ctx, msg, userID)
+
+	case "coordinators":
+		b.handleCoordinators(ctx, msg, userID)
+
+	case "volunteers":
+		b.handleVolunteers(ctx, msg, userID)
+
+	case "revoke":
+		b.handleRevoke(ctx, msg, userID)
+
+	case "ban":
+		b.handleBan(ctx, msg, userID)
+
+	case "unban":
+		b.handleUnban(ctx, msg, userID)
+
+	case "note":
+		b.handleNote(ctx, msg, userID)
 
 	default:
 		b.sendMessage(msg.Chat.ID, "Unknown command. Use /help to see available commands.")
 	}
 }
 
-func (b *Bot) handleMessage(msg *tgbotapi.Message) {
+func (b *Bot) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
+	// Continue a conversation in progress, e.g. a family's intake
+	if msg.Chat.IsPrivate() && b.handleSession(ctx, msg) {
+		return
+	}
+
 	// Check if this is a coordinator forwarding a request
 	if b.isCoordinator(msg.From.ID) && msg.ForwardDate != 0 {
 		// This is a forwarded message from coordinator - treat as new request
-		b.createRequest(msg.Chat.ID, msg.Text, "", "", "")
+		b.createRequest(ctx, msg.Chat.ID, msg.From.ID, msg.Text, "", "", "")
+		return
+	}
+
+	// Families can just start typing to make a request
+	if msg.Chat.IsPrivate() && b.isFamily(ctx, msg.From.ID) {
+		b.startIntake(ctx, msg.Chat.ID)
 		return
 	}
 
@@ -253,7 +380,7 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 }
 
 // handleNewMembers sends a welcome message when someone joins the volunteer group
-func (b *Bot) handleNewMembers(msg *tgbotapi.Message) {
+func (b *Bot) handleNewMembers(ctx context.Context, msg *tgbotapi.Message) {
 	// Only send welcome in the volunteer group chat
 	if msg.Chat.ID != b.volunteerChat {
 		return
@@ -300,21 +427,21 @@ Questions? Reach out to a coordinator. We're glad you're here!`, name)
 			displayName += " " + member.LastName
 		}
 
-		err := b.db.AddVolunteer(member.ID, username, displayName, false)
+		err := b.db.AddVolunteer(ctx, member.ID, username, displayName)
 		if err != nil {
-			log.Printf("Error registering new volunteer %d: %v", member.ID, err)
+			slog.Error("Error registering new volunteer", "user_id", member.ID, "err", err)
 		}
 
 		// Notify coordinators
-		b.notifyCoordinators(fmt.Sprintf("New volunteer joined: %s (@%s, ID: %d)", displayName, username, member.ID))
+		b.notifyCoordinators(fmt.Sprintf("New volunteer joined: %s (@%s, ID: %d)\n\nTo approve: /approve %d", displayName, username, member.ID, member.ID))
 	}
 }
 
-func (b *Bot) handleList(msg *tgbotapi.Message) {
-	requests, err := b.db.GetOpenRequests()
+func (b *Bot) handleList(ctx context.Context, msg *tgbotapi.Message) {
+	requests, err := b.db.GetOpenRequests(ctx)
 	if err != nil {
 		b.sendMessage(msg.Chat.ID, "Error fetching requests. Please try again.")
-		log.Printf("Error fetching open requests: %v", err)
+		slog.Error("Error fetching open requests", "err", err)
 		return
 	}
 
@@ -338,29 +465,22 @@ func (b *Bot) handleList(msg *tgbotapi.Message) {
 		}
 
 		// Show preview of shopping list
-		lines := strings.Split(req.TranslatedText, "\n")
-		shown := 0
 		const maxPreviewItems = 5
-
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-
-			// Show lines that start with bullet or are numbered
-			if strings.HasPrefix(line, "•") || strings.HasPrefix(line, "-") ||
-			   (len(line) > 2 && line[0] >= '0' && line[0] <= '9' && line[1] == '.') {
-				if shown < maxPreviewItems {
-					sb.WriteString(line + "\n")
-					shown++
-				}
+		for i, item := range req.Items {
+			if i == maxPreviewItems {
+				sb.WriteString(fmt.Sprintf("   ...and %d more items\n", len(req.Items)-maxPreviewItems))
+				break
 			}
+			sb.WriteString(translator.FormatItem(item) + "\n")
 		}
 
-		// If no formatted items found, show first non-empty line
-		if shown == 0 {
-			for _, line := range lines {
+		// Requests translated before items existed only have text; show its first line
+		if len(req.Items) == 0 {
+			text := req.TranslatedText
+			if req.NeedsTranslation {
+				text = req.OriginalText
+			}
+			for _, line := range strings.Split(text, "\n") {
 				line = strings.TrimSpace(line)
 				if line != "" {
 					preview := line
@@ -373,28 +493,13 @@ func (b *Bot) handleList(msg *tgbotapi.Message) {
 			}
 		}
 
-		totalItems := countItems(req.TranslatedText)
-		if shown > 0 && shown < totalItems {
-			sb.WriteString(fmt.Sprintf("   ...and %d more items\n", totalItems-shown))
-		}
-
 		sb.WriteString(fmt.Sprintf("\n→ /claim %d or /view %d for full list\n\n", req.ID, req.ID))
 	}
 
 	b.sendMessage(msg.Chat.ID, sb.String())
 }
 
-func (b *Bot) handleClaim(msg *tgbotapi.Message, userID int64) {
-	// Check if volunteer is approved
-	approved, err := b.db.IsVolunteerApproved(userID)
-	if err != nil {
-		log.Printf("Error checking volunteer approval: %v", err)
-	}
-	if !approved && !b.isCoordinator(userID) {
-		b.sendMessage(msg.Chat.ID, "You're not yet approved as a volunteer. Please contact a coordinator.")
-		return
-	}
-
+func (b *Bot) handleClaim(ctx context.Context, msg *tgbotapi.Message, userID int64) {
 	// Parse request ID
 	requestID, err := parseID(msg.CommandArguments())
 	if err != nil {
@@ -402,41 +507,75 @@ func (b *Bot) handleClaim(msg *tgbotapi.Message, userID int64) {
 		return
 	}
 
+	if err := b.claim(ctx, msg.Chat.ID, msg.From, requestID); err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not claim request #%d: %s", requestID, err.Error()))
+	}
+}
+
+// claim claims a request for a volunteer and DMs them the full details.
+// chatID is where the claim was made, for the public acknowledgement.
+func (b *Bot) claim(ctx context.Context, chatID int64, from *tgbotapi.User, requestID int64) error {
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	userID := from.ID
+
+	// Check if volunteer is approved
+	approved, err := b.db.IsVolunteerApproved(ctx, userID)
+	if err != nil {
+		slog.Error("Error checking volunteer approval", "user_id", userID, "err", err)
+	}
+	if !approved && !b.isCoordinator(userID) {
+		return fmt.Errorf("you're not yet approved as a volunteer. Please contact a coordinator")
+	}
+
 	// Get volunteer name
-	volunteerName := msg.From.FirstName
-	if msg.From.LastName != "" {
-		volunteerName += " " + msg.From.LastName
+	volunteerName := from.FirstName
+	if from.LastName != "" {
+		volunteerName += " " + from.LastName
 	}
 
 	// Claim the request
-	err = b.db.ClaimRequest(requestID, userID, volunteerName)
+	err = b.db.ClaimRequest(ctx, requestID, userID, volunteerName)
 	if err != nil {
-		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not claim request #%d: %s", requestID, err.Error()))
-		return
+		if req, getErr := b.db.GetRequest(ctx, requestID); getErr == nil && req.ClaimedBy == userID {
+			return fmt.Errorf("you already claimed this request")
+		}
+		return err
 	}
 
 	// Get request details
-	req, err := b.db.GetRequest(requestID)
+	req, err := b.db.GetRequest(ctx, requestID)
 	if err != nil {
-		b.sendMessage(msg.Chat.ID, "Request claimed, but error fetching details.")
-		return
+		b.sendMessage(chatID, "Request claimed, but error fetching details.")
+		return nil
 	}
 
-	// Get address
-	address, err := b.db.GetAddress(requestID)
-	if err != nil {
-		address = "Address not available - contact coordinator"
+	// Get address and phone
+	address := "Address not available - contact coordinator"
+	phone := ""
+	if contact, err := b.db.GetContact(ctx, requestID); err == nil {
+		if contact.Address != "" {
+			address = contact.Address
+		}
+		phone = contact.Phone
 	}
 
 	// Send confirmation with full details via DM (not group!)
 	response := fmt.Sprintf("✅ CLAIMED! Request #%d is yours.\n\n", requestID)
 	response += fmt.Sprintf("📍 ADDRESS:\n%s\n\n", address)
+	if phone != "" {
+		response += fmt.Sprintf("📞 PHONE: %s\n\n", phone)
+	}
 	response += fmt.Sprintf("💵 BUDGET: %s\n\n", req.Budget)
+	if req.DeliveryWindow != "" {
+		response += fmt.Sprintf("🕐 DELIVERY: %s\n\n", req.DeliveryWindow)
+	}
 	response += "📝 SHOPPING LIST (English):\n"
-	response += req.TranslatedText
+	response += shoppingList(req)
 
 	// Show original Spanish as backup
-	if req.OriginalText != "" && req.OriginalText != req.TranslatedText {
+	if req.OriginalText != "" && !req.NeedsTranslation {
 		response += "\n\n━━━━━━━━━━━━━━━━━━━━━━━━"
 		response += "\n🇪🇸 Original (Spanish):\n"
 		response += req.OriginalText
@@ -444,22 +583,26 @@ func (b *Bot) handleClaim(msg *tgbotapi.Message, userID int64) {
 
 	response += fmt.Sprintf("\n\n━━━━━━━━━━━━━━━━━━━━━━━━\nWhen done: /done %d", requestID)
 
-	b.sendMessage(userID, response) // Send to user's DM
+	b.sendWithKeyboard(userID, response, claimKeyboard(requestID)) // Send to user's DM
 
 	// Acknowledge in group if that's where the claim was made
-	if msg.Chat.ID != userID {
-		b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Request #%d claimed by %s. Details sent via DM.", requestID, volunteerName))
+	if chatID != userID {
+		b.sendMessage(chatID, fmt.Sprintf("✅ Request #%d claimed by %s. Details sent via DM.", requestID, volunteerName))
 	}
 
+	b.updateCard(ctx, requestID)
+	b.tellFamily(ctx, requestID, fmt.Sprintf("🙌 Un voluntario tomó su pedido #%d y pronto irá a la tienda.", requestID))
+
 	// Notify coordinator
 	b.notifyCoordinators(fmt.Sprintf("✋ Request #%d claimed by %s", requestID, volunteerName))
+	return nil
 }
 
-func (b *Bot) handleMine(msg *tgbotapi.Message, userID int64) {
-	requests, err := b.db.GetVolunteerRequests(userID)
+func (b *Bot) handleMine(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	requests, err := b.db.GetVolunteerRequests(ctx, userID)
 	if err != nil {
 		b.sendMessage(msg.Chat.ID, "Error fetching your requests.")
-		log.Printf("Error fetching volunteer requests: %v", err)
+		slog.Error("Error fetching volunteer requests", "user_id", userID, "err", err)
 		return
 	}
 
@@ -474,50 +617,217 @@ func (b *Bot) handleMine(msg *tgbotapi.Message, userID int64) {
 	for _, req := range requests {
 		sb.WriteString(fmt.Sprintf("━━━ #%d • %s\n", req.ID, req.Status))
 		sb.WriteString(fmt.Sprintf("Budget: %s\n", req.Budget))
+		if req.Status == models.StatusClaimed {
+			sb.WriteString(fmt.Sprintf("→ /shopping %d when you're at the store\n", req.ID))
+		}
 		sb.WriteString(fmt.Sprintf("→ /done %d when delivered\n\n", req.ID))
 	}
 
 	b.sendMessage(msg.Chat.ID, sb.String())
 }
 
-func (b *Bot) handleDone(msg *tgbotapi.Message, userID int64) {
+func (b *Bot) handleShopping(ctx context.Context, msg *tgbotapi.Message, userID int64) {
 	requestID, err := parseID(msg.CommandArguments())
 	if err != nil {
-		b.sendMessage(msg.Chat.ID, "Usage: /done <request_id>\nExample: /done 42")
+		b.sendMessage(msg.Chat.ID, "Usage: /shopping <request_id>\nExample: /shopping 42")
 		return
 	}
 
-	err = b.db.CompleteRequest(requestID, userID)
+	if err := b.startShopping(ctx, msg.Chat.ID, msg.From, requestID); err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not update request #%d: %s", requestID, err.Error()))
+	}
+}
+
+func (b *Bot) startShopping(ctx context.Context, chatID int64, from *tgbotapi.User, requestID int64) error {
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	err := b.db.StartShopping(ctx, requestID, from.ID)
 	if err != nil {
-		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not complete request #%d: %s", requestID, err.Error()))
+		return err
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("🛒 Request #%d marked as shopping. Check items off with /checklist %d\nWhen delivered: /done %d", requestID, requestID, requestID))
+	b.updateCard(ctx, requestID)
+
+	// Notify coordinator
+	volunteerName := from.FirstName
+	b.notifyCoordinators(fmt.Sprintf("🛒 %s is shopping for request #%d", volunteerName, requestID))
+	return nil
+}
+
+func (b *Bot) handleDone(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	requestID, err := parseID(msg.CommandArguments())
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Usage: /done <request_id>\nExample: /done 42")
 		return
 	}
 
-	b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Request #%d marked as delivered. Thank you for helping!", requestID))
+	err = b.complete(ctx, msg.Chat.ID, msg.From, requestID, false)
+	if err != nil && !errors.Is(err, errNeedsConfirmation) {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not complete request #%d: %s", requestID, err.Error()))
+	}
+}
+
+// complete marks a request delivered. Unless force is set, a volunteer
+// with unchecked checklist items is asked to confirm first.
+func (b *Bot) complete(ctx context.Context, chatID int64, from *tgbotapi.User, requestID int64, force bool) error {
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	if !force {
+		if req, err := b.db.GetRequest(ctx, requestID); err == nil && req.ClaimedBy == from.ID {
+			if unchecked := uncheckedItems(req); unchecked > 0 {
+				b.sendWithKeyboard(chatID, fmt.Sprintf("⚠️ %d of %d items on request #%d aren't checked off yet. Deliver anyway?",
+					unchecked, len(req.Items), requestID), deliverAnywayKeyboard(requestID))
+				return errNeedsConfirmation
+			}
+		}
+	}
+
+	err := b.db.CompleteRequest(ctx, requestID, from.ID)
+	if err != nil {
+		return err
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("✅ Request #%d marked as delivered. Thank you for helping!", requestID))
+	b.updateCard(ctx, requestID)
 
 	// Notify coordinator
-	volunteerName := msg.From.FirstName
-	b.notifyCoordinators(fmt.Sprintf("✅ Request #%d delivered by %s", requestID, volunteerName))
+	volunteerName := from.FirstName
+	delivered := fmt.Sprintf("✅ Request #%d delivered by %s", requestID, volunteerName)
+	if subs := b.substitutionSummary(ctx, requestID); subs != "" {
+		delivered += "\n\n" + subs
+	}
+	b.notifyCoordinators(delivered)
 
 	// Notify volunteer group
 	b.sendMessage(b.volunteerChat, fmt.Sprintf("✅ Request #%d delivered!", requestID))
+	return nil
 }
 
-func (b *Bot) handleCancel(msg *tgbotapi.Message, userID int64) {
+func (b *Bot) handleCancel(ctx context.Context, msg *tgbotapi.Message, userID int64) {
 	requestID, err := parseID(msg.CommandArguments())
 	if err != nil {
 		b.sendMessage(msg.Chat.ID, "Usage: /cancel <request_id>\nExample: /cancel 42")
 		return
 	}
 
-	// For now, cancellation requires coordinator approval
-	// Just notify the coordinator
-	volunteerName := msg.From.FirstName
-	b.notifyCoordinators(fmt.Sprintf("⚠️ %s wants to cancel claim on request #%d", volunteerName, requestID))
-	b.sendMessage(msg.Chat.ID, "Cancellation request sent to coordinator. They will release the claim if appropriate.")
+	if err := b.cancelClaim(ctx, msg.Chat.ID, msg.From, requestID); err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not cancel request #%d: %s", requestID, err.Error()))
+	}
+}
+
+// cancelClaim releases a volunteer's own claim, or asks coordinators to
+// release it when cancellations need approval.
+func (b *Bot) cancelClaim(ctx context.Context, chatID int64, from *tgbotapi.User, requestID int64) error {
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	volunteerName := from.FirstName
+
+	if b.cancelNeedsApproval {
+		req, err := b.db.GetRequest(ctx, requestID)
+		if err != nil || req.ClaimedBy != from.ID {
+			return fmt.Errorf("you don't have this request claimed")
+		}
+
+		b.notifyCoordinators(fmt.Sprintf("⚠️ %s wants to cancel claim on request #%d\n\nTo release it: /release %d", volunteerName, requestID, requestID))
+		b.sendMessage(chatID, "Cancellation request sent to coordinator. They will release the claim if appropriate.")
+		return nil
+	}
+
+	err := b.db.ReleaseClaim(ctx, requestID, from.ID, false)
+	if err != nil {
+		return err
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("↩️ Your claim on request #%d was released. Thanks for letting us know!", requestID))
+	b.notifyCoordinators(fmt.Sprintf("↩️ %s released their claim on request #%d", volunteerName, requestID))
+	b.repostRequest(ctx, requestID)
+	return nil
+}
+
+func (b *Bot) handleRelease(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can release claims.")
+		return
+	}
+
+	requestID, err := parseID(msg.CommandArguments())
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Usage: /release <request_id>\nExample: /release 42")
+		return
+	}
+
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Request #%d not found.", requestID))
+		return
+	}
+
+	err = b.db.ReleaseClaim(ctx, requestID, userID, true)
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not release request #%d: %s", requestID, err.Error()))
+		return
+	}
+
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("↩️ Request #%d released and re-posted to volunteers.", requestID))
+	if req.ClaimedBy != 0 && req.ClaimedBy != userID {
+		b.sendMessage(req.ClaimedBy, fmt.Sprintf("↩️ A coordinator released your claim on request #%d. No need to shop for it.", requestID))
+	}
+	b.repostRequest(ctx, requestID)
+}
+
+func (b *Bot) handleCancelRequest(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can cancel requests.")
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(msg.CommandArguments()), " ", 2)
+	requestID, err := parseID(parts[0])
+	if err != nil || len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		b.sendMessage(msg.Chat.ID, "Usage: /cancelrequest <request_id> <reason>\nExample: /cancelrequest 42 family no longer needs it")
+		return
+	}
+	reason := strings.TrimSpace(parts[1])
+
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Request #%d not found.", requestID))
+		return
+	}
+
+	err = b.db.CancelRequest(ctx, requestID)
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not cancel request #%d: %s", requestID, err.Error()))
+		return
+	}
+
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("❌ Request #%d cancelled.", requestID))
+	b.updateCard(ctx, requestID)
+
+	// Let the volunteer know they can stop shopping
+	if req.ClaimedBy != 0 {
+		b.sendMessage(req.ClaimedBy, fmt.Sprintf("❌ Request #%d was cancelled by a coordinator: %s\n\nNo need to shop or deliver.", requestID, reason))
+	}
+
+	// Let the group know it's no longer available, if it ever saw it
+	if req.CardMessageID != 0 {
+		b.sendMessage(b.volunteerChat, fmt.Sprintf("❌ Request #%d was cancelled.", requestID))
+	}
+
+	b.notifyCoordinators(fmt.Sprintf("❌ Request #%d cancelled by %s: %s", requestID, msg.From.FirstName, reason))
 }
 
-func (b *Bot) handleNew(msg *tgbotapi.Message, userID int64) {
+func (b *Bot) handleNew(ctx context.Context, msg *tgbotapi.Message, userID int64) {
 	if !b.isCoordinator(userID) {
 		b.sendMessage(msg.Chat.ID, "Only coordinators can create new requests.")
 		return
@@ -535,10 +845,10 @@ func (b *Bot) handleNew(msg *tgbotapi.Message, userID int64) {
 		return
 	}
 
-	b.createRequest(msg.Chat.ID, text, "", "", "")
+	b.createRequest(ctx, msg.Chat.ID, userID, text, "", "", "")
 }
 
-func (b *Bot) handleAddress(msg *tgbotapi.Message, userID int64) {
+func (b *Bot) handleAddress(ctx context.Context, msg *tgbotapi.Message, userID int64) {
 	if !b.isCoordinator(userID) {
 		b.sendMessage(msg.Chat.ID, "Only coordinators can set addresses.")
 		return
@@ -558,7 +868,11 @@ func (b *Bot) handleAddress(msg *tgbotapi.Message, userID int64) {
 	}
 
 	address := strings.TrimSpace(parts[1])
-	err = b.db.SaveAddress(requestID, address)
+
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	err = b.db.SaveContact(ctx, requestID, address, "")
 	if err != nil {
 		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Error saving address: %v", err))
 		return
@@ -567,21 +881,65 @@ func (b *Bot) handleAddress(msg *tgbotapi.Message, userID int64) {
 	b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Address saved for request #%d", requestID))
 }
 
-func (b *Bot) handleView(msg *tgbotapi.Message, userID int64) {
+func (b *Bot) handlePhone(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can set phone numbers.")
+		return
+	}
+
+	args := msg.CommandArguments()
+	parts := strings.SplitN(args, " ", 2)
+	if len(parts) < 2 {
+		b.sendMessage(msg.Chat.ID, "Usage: /phone <request_id> <number>\nExample: /phone 1 651-555-0123")
+		return
+	}
+
+	requestID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Invalid request ID.")
+		return
+	}
+
+	phone := strings.TrimSpace(parts[1])
+
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	err = b.db.SaveContact(ctx, requestID, "", phone)
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Error saving phone: %v", err))
+		return
+	}
+
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Phone saved for request #%d", requestID))
+}
+
+func (b *Bot) handleView(ctx context.Context, msg *tgbotapi.Message, userID int64) {
 	requestID, err := parseID(msg.CommandArguments())
 	if err != nil {
 		b.sendMessage(msg.Chat.ID, "Usage: /view <request_id>\nExample: /view 1")
 		return
 	}
 
-	req, err := b.db.GetRequest(requestID)
+	if err := b.view(ctx, msg.Chat.ID, userID, requestID); err != nil {
+		b.sendMessage(msg.Chat.ID, err.Error())
+	}
+}
+
+// view shows a request's full list. Open requests are shown in chatID;
+// others only go to the user's DM. Requests that haven't been posted yet
+// are only visible to coordinators.
+func (b *Bot) view(ctx context.Context, chatID int64, userID int64, requestID int64) error {
+	req, err := b.db.GetRequest(ctx, requestID)
 	if err != nil {
-		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Request #%d not found.", requestID))
-		return
+		return fmt.Errorf("Request #%d not found.", requestID)
 	}
 
 	isCoord := b.isCoordinator(userID)
-	isUnclaimed := req.Status == "posted" || req.Status == "new"
+	if !isCoord && (req.Status == models.StatusNew || req.Status == models.StatusPendingReview) {
+		return fmt.Errorf("Request #%d not found.", requestID)
+	}
+	isUnclaimed := req.Status == models.StatusPosted
 
 	// Build the public response (no address)
 	var sb strings.Builder
@@ -590,99 +948,143 @@ func (b *Bot) handleView(msg *tgbotapi.Message, userID int64) {
 	if req.Budget != "" {
 		sb.WriteString(fmt.Sprintf("Budget: %s\n", req.Budget))
 	}
+	if req.DeliveryWindow != "" {
+		sb.WriteString(fmt.Sprintf("Delivery: %s\n", req.DeliveryWindow))
+	}
 
 	sb.WriteString("\n📝 Shopping list (English):\n")
-	sb.WriteString(req.TranslatedText)
+	sb.WriteString(shoppingList(req))
 
 	// Show original Spanish as backup
-	if req.OriginalText != "" && req.OriginalText != req.TranslatedText {
+	if req.OriginalText != "" && !req.NeedsTranslation {
 		sb.WriteString("\n\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
 		sb.WriteString("\n🇪🇸 Original (Spanish):\n")
 		sb.WriteString(req.OriginalText)
 	}
 
+	if subs := b.substitutionSummary(ctx, requestID); subs != "" {
+		sb.WriteString("\n\n" + subs)
+	}
+
 	if req.ClaimedByName != "" {
 		sb.WriteString(fmt.Sprintf("\n\nClaimed by: %s", req.ClaimedByName))
 	}
 
 	if isUnclaimed {
 		// Unclaimed: show in group for everyone
-		b.sendMessage(msg.Chat.ID, sb.String())
+		b.sendMessage(chatID, sb.String())
 
-		// Coordinator also gets address via DM
+		// Coordinator also gets contact details via DM
 		if isCoord {
-			address, _ := b.db.GetAddress(requestID)
-			if address != "" {
-				b.sendMessage(userID, fmt.Sprintf("📍 Address for #%d: %s", requestID, address))
+			if contact, err := b.db.GetContact(ctx, requestID); err == nil {
+				if contact.Address != "" {
+					b.sendMessage(userID, fmt.Sprintf("📍 Address for #%d: %s", requestID, contact.Address))
+				}
+				if contact.Phone != "" {
+					b.sendMessage(userID, fmt.Sprintf("📞 Phone for #%d: %s", requestID, contact.Phone))
+				}
 			}
 		}
 	} else {
 		// Claimed: send full details to DM
 		if isCoord {
-			address, _ := b.db.GetAddress(requestID)
-			if address != "" {
-				sb.WriteString(fmt.Sprintf("\n\n📍 Address: %s", address))
+			if contact, err := b.db.GetContact(ctx, requestID); err == nil {
+				if contact.Address != "" {
+					sb.WriteString(fmt.Sprintf("\n\n📍 Address: %s", contact.Address))
+				}
+				if contact.Phone != "" {
+					sb.WriteString(fmt.Sprintf("\n📞 Phone: %s", contact.Phone))
+				}
 			}
 		}
 		b.sendMessage(userID, sb.String())
-		if msg.Chat.ID != userID {
-			b.sendMessage(msg.Chat.ID, "📬 Details sent to your DM.")
+		if chatID != userID {
+			b.sendMessage(chatID, "📬 Details sent to your DM.")
 		}
 	}
+	return nil
 }
 
-func (b *Bot) createRequest(chatID int64, spanishText string, budget string, zone string, address string) {
+// createRequest creates, translates and posts a request. createdBy is the
+// coordinator entering it, who is asked about substitutions.
+func (b *Bot) createRequest(ctx context.Context, chatID int64, createdBy int64, spanishText string, budget string, zone string, address string) {
 	// Extract budget if present in text
 	if budget == "" {
 		budget = extractBudget(spanishText)
 	}
 
 	// Create the request in DB
-	req, err := b.db.CreateRequest(spanishText, budget, zone)
+	req, err := b.db.CreateRequest(ctx, spanishText, budget, zone, createdBy)
 	if err != nil {
 		b.sendMessage(chatID, "Error creating request. Please try again.")
-		log.Printf("Error creating request: %v", err)
+		slog.Error("Error creating request", "user_id", createdBy, "err", err)
 		return
 	}
 
 	// Save address if provided
 	if address != "" {
-		err = b.db.SaveAddress(req.ID, address)
+		err = b.db.SaveContact(ctx, req.ID, address, "")
 		if err != nil {
-			log.Printf("Error saving address: %v", err)
+			slog.Error("Error saving address", "request_id", req.ID, "err", err)
 		}
 	}
 
 	b.sendMessage(chatID, fmt.Sprintf("📝 Request #%d created. Translating...", req.ID))
 
 	// Translate using LLM (extracts PII like address/phone)
-	result, err := b.translator.TranslateRequest(spanishText)
-	if err != nil {
-		b.sendMessage(chatID, fmt.Sprintf("Error translating request #%d: %v", req.ID, err))
-		log.Printf("Error translating request: %v", err)
-		return
+	result, needsTranslation := b.translate(ctx, req.ID, spanishText)
+	if needsTranslation {
+		b.sendMessage(chatID, fmt.Sprintf("⚠️ Could not translate request #%d. It will go out in Spanish, flagged for a bilingual volunteer, and translation will be retried.", req.ID))
 	}
 
 	// Update with cleaned translation (safe for public posting)
-	err = b.db.UpdateRequestTranslation(req.ID, result.CleanedText)
+	next := models.StatusPosted
+	if b.reviewBeforePosting {
+		next = models.StatusPendingReview
+	}
+	err = b.db.UpdateRequestTranslation(ctx, req.ID, result.CleanedText, result.Items, result.Notes, next, needsTranslation)
 	if err != nil {
-		log.Printf("Error updating translation: %v", err)
+		b.sendMessage(chatID, fmt.Sprintf("Error saving translation for request #%d: %v", req.ID, err))
+		slog.Error("Error updating translation", "request_id", req.ID, "err", err)
+		return
+	}
+
+	// Use the budget the translator found if none was given or spotted
+	if budget == "" && result.Budget != "" {
+		budget = result.Budget
+		if err := b.db.UpdateRequestBudget(ctx, req.ID, budget); err != nil {
+			slog.Error("Error saving budget", "request_id", req.ID, "err", err)
+		}
 	}
 
-	// Save extracted address if found (unless already provided)
+	// Save extracted address (unless already provided) and phone if found
+	extractedAddress := ""
 	if address == "" && result.Address != "" {
 		address = result.Address
-		err = b.db.SaveAddress(req.ID, address)
+		extractedAddress = result.Address
+	}
+	if extractedAddress != "" || result.Phone != "" {
+		err = b.db.SaveContact(ctx, req.ID, extractedAddress, result.Phone)
 		if err != nil {
-			log.Printf("Error saving extracted address: %v", err)
+			slog.Error("Error saving extracted contact", "request_id", req.ID, "err", err)
 		} else {
-			log.Printf("Extracted and saved address for request #%d", req.ID)
+			slog.Info("Saved extracted contact", "request_id", req.ID,
+				"address", extractedAddress != "", "phone", result.Phone != "")
 		}
 	}
 
+	// Check the card for leftover PII before anyone else sees it
+	if b.reviewBeforePosting {
+		b.requestReview(ctx, req.ID, "👀 REVIEW BEFORE POSTING - check for names, addresses or phone numbers")
+		return
+	}
+
 	// Format and post to volunteer channel (only cleaned translation, no PII)
-	formatted := b.translator.FormatRequest(req.ID, zone, budget, result.CleanedText)
-	b.sendMessage(b.volunteerChat, formatted)
+	list := result.CleanedText
+	if needsTranslation {
+		list = needsTranslationBanner + spanishText
+	}
+	b.postCard(ctx, req.ID, zone, budget, list)
 
 	// Notify coordinator
 	if address != "" {
@@ -692,67 +1094,56 @@ func (b *Bot) createRequest(chatID int64, spanishText string, budget string, zon
 	}
 }
 
-func (b *Bot) handleStatus(msg *tgbotapi.Message, userID int64) {
-	if !b.isCoordinator(userID) {
-		b.sendMessage(msg.Chat.ID, "Only coordinators can view full status.")
+// repostRequest posts a released request back to the volunteer chat as a
+// fresh card, retiring the old one so only one open card exists.
+func (b *Bot) repostRequest(ctx context.Context, requestID int64) {
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		slog.Error("Error fetching request for repost", "request_id", requestID, "err", err)
 		return
 	}
 
-	// Get counts by status
-	// This is a simplified version - could be expanded
-	open, _ := b.db.GetOpenRequests()
-
-	b.sendMessage(msg.Chat.ID, fmt.Sprintf("📊 STATUS\n\nOpen requests: %d\n\nUse /list to see details.", len(open)))
+	b.editCard(req, "↩️ Released - re-posted below", false)
+	b.postCard(ctx, req.ID, req.Zone, req.Budget, shoppingList(req))
 }
 
-func (b *Bot) handleApprove(msg *tgbotapi.Message, userID int64) {
+func (b *Bot) handleStatus(ctx context.Context, msg *tgbotapi.Message, userID int64) {
 	if !b.isCoordinator(userID) {
-		b.sendMessage(msg.Chat.ID, "Only coordinators can approve volunteers.")
-		return
-	}
-
-	args := msg.CommandArguments()
-	if args == "" {
-		b.sendMessage(msg.Chat.ID, "Usage: /approve <telegram_user_id>")
-		return
-	}
-
-	volunteerID, err := strconv.ParseInt(args, 10, 64)
-	if err != nil {
-		b.sendMessage(msg.Chat.ID, "Invalid user ID. Must be a number.")
+		b.sendMessage(msg.Chat.ID, "Only coordinators can view full status.")
 		return
 	}
 
-	err = b.db.AddVolunteer(volunteerID, "", "", true)
-	if err != nil {
-		b.sendMessage(msg.Chat.ID, "Error approving volunteer.")
-		return
-	}
+	// Get counts by status
+	// This is a simplified version - could be expanded
+	open, _ := b.db.GetOpenRequests(ctx)
 
-	b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Volunteer %d approved.", volunteerID))
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("📊 STATUS\n\nOpen requests: %d\n\nUse /list to see details.", len(open)))
 }
 
 func (b *Bot) sendMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
+	msg := tgbotapi.NewMessage(chatID, b.scrubForChat(chatID, text))
 	_, err := b.api.Send(msg)
 	if err != nil {
-		log.Printf("Error sending message: %v", err)
+		slog.Error("Error sending message", "chat_id", chatID, "err", err)
 	}
 }
 
-func (b *Bot) notifyCoordinators(text string) {
-	for _, coordID := range b.coordinatorIDs {
-		b.sendMessage(coordID, text)
+// sendWithKeyboard sends a message with inline buttons and returns its ID (0 on error)
+func (b *Bot) sendWithKeyboard(chatID int64, text string, keyboard tgbotapi.InlineKeyboardMarkup) int {
+	msg := tgbotapi.NewMessage(chatID, b.scrubForChat(chatID, text))
+	msg.ReplyMarkup = keyboard
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		slog.Error("Error sending message", "chat_id", chatID, "err", err)
+		return 0
 	}
+	return sent.MessageID
 }
 
-func (b *Bot) isCoordinator(userID int64) bool {
-	for _, id := range b.coordinatorIDs {
-		if id == userID {
-			return true
-		}
+func (b *Bot) notifyCoordinators(text string) {
+	for _, coordID := range b.coordinators() {
+		b.sendMessage(coordID, text)
 	}
-	return false
 }
 
 // Helper functions
@@ -765,14 +1156,17 @@ func parseID(args string) (int64, error) {
 	return strconv.ParseInt(args, 10, 64)
 }
 
-func countItems(text string) int {
-	count := 0
-	for _, line := range strings.Split(text, "\n") {
-		if strings.HasPrefix(strings.TrimSpace(line), "•") {
-			count++
-		}
+// shoppingList renders a request's list from its items, falling back to
+// the stored text for requests translated before items existed, or to the
+// family's Spanish for requests that still need translating
+func shoppingList(req *models.Request) string {
+	if req.NeedsTranslation {
+		return needsTranslationBanner + req.OriginalText
+	}
+	if len(req.Items) == 0 {
+		return req.TranslatedText
 	}
-	return count
+	return translator.FormatShoppingList(req.Items, req.Notes)
 }
 
 func extractBudget(text string) string {
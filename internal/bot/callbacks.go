@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Callback data is "<action>:<request_id>", e.g. "claim:42". Checklist
+// item and substitution buttons carry the item or substitution ID instead.
+const (
+	actionClaim         = "claim"
+	actionView          = "view"
+	actionShopping      = "shop"
+	actionDone          = "done"
+	actionDeliverAnyway = "deliver"
+	actionRelease       = "release"
+	actionChecklist     = "checklist"
+	actionItem          = "item"
+	actionSubApprove    = "subok"
+	actionSubReject     = "subno"
+	actionApprove       = "approve"
+	actionEdit          = "edit"
+	actionReject        = "reject"
+)
+
+// cardKeyboard is attached to request cards in the volunteer chat
+func cardKeyboard(requestID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✋ Claim", callbackData(actionClaim, requestID)),
+			tgbotapi.NewInlineKeyboardButtonData("📝 View full list", callbackData(actionView, requestID)),
+		),
+	)
+}
+
+// claimKeyboard is attached to the claim DM sent to a volunteer
+func claimKeyboard(requestID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🛒 Start shopping", callbackData(actionShopping, requestID)),
+			tgbotapi.NewInlineKeyboardButtonData("✅ Delivered", callbackData(actionDone, requestID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📋 Checklist", callbackData(actionChecklist, requestID)),
+			tgbotapi.NewInlineKeyboardButtonData("↩️ Release", callbackData(actionRelease, requestID)),
+		),
+	)
+}
+
+// deliverAnywayKeyboard confirms delivering a request with unchecked items
+func deliverAnywayKeyboard(requestID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Deliver anyway", callbackData(actionDeliverAnyway, requestID)),
+			tgbotapi.NewInlineKeyboardButtonData("📋 Checklist", callbackData(actionChecklist, requestID)),
+		),
+	)
+}
+
+// substitutionKeyboard lets the coordinator relay the family's answer
+func substitutionKeyboard(subID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Family approves", callbackData(actionSubApprove, subID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Family says no", callbackData(actionSubReject, subID)),
+		),
+	)
+}
+
+// reviewKeyboard lets coordinators approve, correct or reject a pending request
+func reviewKeyboard(requestID int64) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Approve & post", callbackData(actionApprove, requestID)),
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Edit", callbackData(actionEdit, requestID)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Reject", callbackData(actionReject, requestID)),
+		),
+	)
+}
+
+func callbackData(action string, requestID int64) string {
+	return fmt.Sprintf("%s:%d", action, requestID)
+}
+
+func parseCallbackData(data string) (string, int64, error) {
+	action, idStr, ok := strings.Cut(data, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("malformed callback data %q", data)
+	}
+	requestID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("malformed callback data %q", data)
+	}
+	return action, requestID, nil
+}
+
+// handleCallback handles an inline keyboard button press
+func (b *Bot) handleCallback(ctx context.Context, cq *tgbotapi.CallbackQuery) {
+	action, requestID, err := parseCallbackData(cq.Data)
+	if err != nil {
+		slog.Warn("Ignoring callback", "err", err)
+		b.answerCallback(cq.ID, "Unknown action", false)
+		return
+	}
+
+	// Ignore a second tap on the same button while the first is still running
+	key := fmt.Sprintf("%d:%s", cq.From.ID, cq.Data)
+	if !b.beginCallback(key) {
+		b.answerCallback(cq.ID, "Already on it...", false)
+		return
+	}
+	defer b.endCallback(key)
+
+	// Buttons outside a chat (inline mode) have no message; reply by DM
+	chatID := cq.From.ID
+	if cq.Message != nil {
+		chatID = cq.Message.Chat.ID
+	}
+
+	var answer string
+	switch action {
+	case actionClaim:
+		err = b.claim(ctx, chatID, cq.From, requestID)
+		answer = "✅ Claimed! Details sent via DM."
+	case actionView:
+		// Always reply privately so button taps don't flood the group
+		err = b.view(ctx, cq.From.ID, cq.From.ID, requestID)
+		answer = "📬 Sent to your DM."
+	case actionShopping:
+		err = b.startShopping(ctx, chatID, cq.From, requestID)
+		answer = "🛒 Marked as shopping"
+	case actionDone:
+		err = b.complete(ctx, chatID, cq.From, requestID, false)
+		answer = "✅ Marked as delivered"
+	case actionDeliverAnyway:
+		err = b.complete(ctx, chatID, cq.From, requestID, true)
+		answer = "✅ Marked as delivered"
+	case actionChecklist:
+		err = b.checklist(ctx, chatID, cq.From, requestID)
+		answer = "📋 Checklist sent via DM"
+	case actionItem:
+		b.toggleItem(ctx, cq, requestID)
+		return
+	case actionSubApprove, actionSubReject:
+		b.decideSubstitution(ctx, cq, requestID, action == actionSubApprove)
+		return
+	case actionRelease:
+		err = b.cancelClaim(ctx, chatID, cq.From, requestID)
+		answer = "↩️ Done"
+	case actionApprove:
+		err = b.approveRequest(ctx, cq.From.ID, requestID)
+		answer = "📢 Posted to volunteers"
+	case actionEdit:
+		err = b.startEdit(ctx, cq.From.ID, requestID)
+		answer = "✏️ Send the corrected text"
+	case actionReject:
+		err = b.rejectRequest(ctx, cq.From.ID, requestID)
+		answer = "❌ Rejected"
+	default:
+		b.answerCallback(cq.ID, "Unknown action", false)
+		return
+	}
+
+	if errors.Is(err, errNeedsConfirmation) {
+		b.answerCallback(cq.ID, "⚠️ Some items aren't checked off yet", false)
+		return
+	}
+	if err != nil {
+		b.answerCallback(cq.ID, fmt.Sprintf("Request #%d: %s", requestID, err.Error()), true)
+		return
+	}
+	b.answerCallback(cq.ID, answer, false)
+}
+
+func (b *Bot) beginCallback(key string) bool {
+	b.inFlightMutex.Lock()
+	defer b.inFlightMutex.Unlock()
+	if b.inFlight[key] {
+		return false
+	}
+	b.inFlight[key] = true
+	return true
+}
+
+func (b *Bot) endCallback(key string) {
+	b.inFlightMutex.Lock()
+	defer b.inFlightMutex.Unlock()
+	delete(b.inFlight, key)
+}
+
+// answerCallback stops the button's loading spinner, optionally as an alert popup
+func (b *Bot) answerCallback(callbackID string, text string, alert bool) {
+	cfg := tgbotapi.NewCallback(callbackID, text)
+	cfg.ShowAlert = alert
+	if _, err := b.api.Request(cfg); err != nil {
+		slog.Error("Error answering callback", "err", err)
+	}
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/models"
+	"github.com/centromex/grocery-bot/internal/pii"
+)
+
+// postCard posts a request card to the volunteer chat and remembers its
+// message ID so the card can be edited as the request changes state.
+func (b *Bot) postCard(ctx context.Context, requestID int64, zone, budget, translatedText string) {
+	formatted := b.translator.FormatRequest(requestID, zone, budget, translatedText)
+	messageID := b.sendWithKeyboard(b.volunteerChat, formatted, cardKeyboard(requestID))
+	if messageID == 0 {
+		return
+	}
+
+	if err := b.db.SetCardMessageID(ctx, requestID, messageID); err != nil {
+		slog.Error("Error saving card message", "request_id", requestID, "err", err)
+	}
+}
+
+// updateCard edits a request's card in the volunteer chat to show its
+// current status. Open requests get their buttons back; anything else is
+// struck through so nobody tries to claim it.
+func (b *Bot) updateCard(ctx context.Context, requestID int64) {
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		slog.Error("Error fetching request for card update", "request_id", requestID, "err", err)
+		return
+	}
+
+	switch req.Status {
+	case models.StatusPosted:
+		b.editCard(req, "", true)
+	case models.StatusClaimed:
+		b.editCard(req, fmt.Sprintf("✋ Claimed by %s", req.ClaimedByName), false)
+	case models.StatusShopping:
+		b.editCard(req, fmt.Sprintf("🛒 %s is shopping", req.ClaimedByName), false)
+	case models.StatusDelivered:
+		b.editCard(req, "✅ Delivered", false)
+	case models.StatusCancelled:
+		b.editCard(req, "❌ Cancelled", false)
+	}
+}
+
+// editCard redraws a request's card: open with its buttons, or struck
+// through with a note, e.g. before the request is re-posted as a fresh card
+func (b *Bot) editCard(req *models.Request, note string, open bool) {
+	if req.CardMessageID == 0 {
+		return
+	}
+
+	// Already flagged when the card was first posted, so redact quietly
+	card, _ := pii.Scrub(b.translator.FormatRequest(req.ID, req.Zone, req.Budget, shoppingList(req)))
+
+	var edit tgbotapi.EditMessageTextConfig
+	if open {
+		edit = tgbotapi.NewEditMessageTextAndMarkup(b.volunteerChat, req.CardMessageID, card, cardKeyboard(req.ID))
+	} else {
+		text := "<s>" + html.EscapeString(card) + "</s>"
+		if note != "" {
+			text += "\n\n<b>" + html.EscapeString(note) + "</b>"
+		}
+		edit = tgbotapi.NewEditMessageText(b.volunteerChat, req.CardMessageID, text)
+		edit.ParseMode = tgbotapi.ModeHTML
+	}
+
+	if _, err := b.api.Send(edit); err != nil {
+		slog.Error("Error editing card", "request_id", req.ID, "err", err)
+	}
+}
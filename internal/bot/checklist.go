@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/models"
+	"github.com/centromex/grocery-bot/internal/translator"
+)
+
+// errNeedsConfirmation is returned by complete when it asked the volunteer
+// to confirm delivering a request with unchecked items
+var errNeedsConfirmation = errors.New("waiting for confirmation")
+
+var itemStatusIcons = map[models.ItemStatus]string{
+	models.ItemPending:     "⬜",
+	models.ItemFound:       "✅",
+	models.ItemSubstituted: "🔄",
+	models.ItemUnavailable: "❌",
+}
+
+func (b *Bot) handleChecklist(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	requestID, err := parseID(msg.CommandArguments())
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Usage: /checklist <request_id>\nExample: /checklist 42")
+		return
+	}
+
+	if err := b.checklist(ctx, msg.Chat.ID, msg.From, requestID); err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not open checklist for request #%d: %s", requestID, err.Error()))
+	}
+}
+
+// checklist DMs the volunteer an interactive checklist for a request they
+// claimed. Each item is a button that cycles its status; the message is
+// edited in place as they go.
+func (b *Bot) checklist(ctx context.Context, chatID int64, from *tgbotapi.User, requestID int64) error {
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		return fmt.Errorf("request not found")
+	}
+	if req.ClaimedBy != from.ID || (req.Status != models.StatusClaimed && req.Status != models.StatusShopping) {
+		return fmt.Errorf("you don't have this request claimed")
+	}
+	if len(req.Items) == 0 {
+		return fmt.Errorf("this request has no item list. Use /view %d to see it", requestID)
+	}
+
+	b.sendWithKeyboard(from.ID, checklistText(req), checklistKeyboard(req))
+	if chatID != from.ID {
+		b.sendMessage(chatID, "📬 Checklist sent to your DM.")
+	}
+	return nil
+}
+
+// toggleItem handles a checklist button. It answers the callback itself
+// since the button carries an item ID rather than a request ID.
+func (b *Bot) toggleItem(ctx context.Context, cq *tgbotapi.CallbackQuery, itemID int64) {
+	item, err := b.db.CycleItemStatus(ctx, itemID, cq.From.ID)
+	if err != nil {
+		b.answerCallback(cq.ID, err.Error(), true)
+		return
+	}
+	b.answerCallback(cq.ID, fmt.Sprintf("%s %s", itemStatusIcons[item.Status], item.Name), false)
+
+	if cq.Message == nil {
+		return
+	}
+
+	req, err := b.db.GetRequest(ctx, item.RequestID)
+	if err != nil {
+		slog.Error("Error fetching request for checklist", "request_id", item.RequestID, "err", err)
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(cq.Message.Chat.ID, cq.Message.MessageID, checklistText(req), checklistKeyboard(req))
+	if _, err := b.api.Send(edit); err != nil {
+		slog.Error("Error editing checklist", "request_id", req.ID, "err", err)
+	}
+}
+
+func checklistText(req *models.Request) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🛒 CHECKLIST - Request #%d\n", req.ID))
+	if req.Budget != "" {
+		sb.WriteString(fmt.Sprintf("💵 %s\n", req.Budget))
+	}
+	sb.WriteString("\n")
+
+	for _, item := range req.Items {
+		sb.WriteString(itemStatusIcons[item.Status] + " " + strings.TrimPrefix(translator.FormatItem(item), "• ") + "\n")
+		if item.Notes != "" {
+			sb.WriteString("   ↳ " + item.Notes + "\n")
+		}
+	}
+
+	if req.Notes != "" {
+		sb.WriteString("\nNOTES\n" + req.Notes + "\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%d of %d checked • tap an item: ✅ found → 🔄 substituted → ❌ unavailable",
+		len(req.Items)-uncheckedItems(req), len(req.Items)))
+	sb.WriteString(fmt.Sprintf("\nOut of something? /sub %d <item> -> <replacement>", req.ID))
+	return sb.String()
+}
+
+func checklistKeyboard(req *models.Request) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, item := range req.Items {
+		label := itemStatusIcons[item.Status] + " " + item.Name
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, callbackData(actionItem, item.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Delivered", callbackData(actionDone, req.ID)),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// uncheckedItems counts items the volunteer hasn't marked yet
+func uncheckedItems(req *models.Request) int {
+	count := 0
+	for _, item := range req.Items {
+		if item.Status == models.ItemPending {
+			count++
+		}
+	}
+	return count
+}
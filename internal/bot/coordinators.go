@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// Coordinators come from two places. COORDINATOR_IDS are super-admins:
+// they can't be demoted and are the only ones who can /promote and
+// /demote. Everyone else is promoted in the database; those roles are
+// loaded at startup and kept in step as they change, so checks don't need
+// a query.
+
+// loadCoordinators reads the coordinators promoted in the database
+func (b *Bot) loadCoordinators(ctx context.Context) error {
+	coordinators, err := b.db.GetCoordinators(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load coordinators: %w", err)
+	}
+
+	b.coordinatorsMutex.Lock()
+	defer b.coordinatorsMutex.Unlock()
+	b.promoted = make(map[int64]bool, len(coordinators))
+	for _, c := range coordinators {
+		b.promoted[c.TelegramID] = true
+	}
+	return nil
+}
+
+func (b *Bot) isCoordinator(userID int64) bool {
+	if b.isSuperAdmin(userID) {
+		return true
+	}
+	b.coordinatorsMutex.RLock()
+	defer b.coordinatorsMutex.RUnlock()
+	return b.promoted[userID]
+}
+
+// isSuperAdmin reports whether a user is one of the configured COORDINATOR_IDS
+func (b *Bot) isSuperAdmin(userID int64) bool {
+	return slices.Contains(b.coordinatorIDs, userID)
+}
+
+// coordinators returns every coordinator, super-admins first
+func (b *Bot) coordinators() []int64 {
+	ids := slices.Clone(b.coordinatorIDs)
+
+	b.coordinatorsMutex.RLock()
+	defer b.coordinatorsMutex.RUnlock()
+	promoted := make([]int64, 0, len(b.promoted))
+	for id := range b.promoted {
+		if !slices.Contains(ids, id) {
+			promoted = append(promoted, id)
+		}
+	}
+	slices.Sort(promoted)
+	return append(ids, promoted...)
+}
+
+func (b *Bot) handlePromote(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	b.setCoordinator(ctx, msg, userID, true)
+}
+
+func (b *Bot) handleDemote(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	b.setCoordinator(ctx, msg, userID, false)
+}
+
+// setCoordinator handles /promote and /demote
+func (b *Bot) setCoordinator(ctx context.Context, msg *tgbotapi.Message, userID int64, promote bool) {
+	command := "/demote"
+	if promote {
+		command = "/promote"
+	}
+
+	if !b.isSuperAdmin(userID) {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Only the coordinators set in COORDINATOR_IDS can use %s.", command))
+		return
+	}
+
+	targetID, err := strconv.ParseInt(strings.TrimSpace(msg.CommandArguments()), 10, 64)
+	if err != nil || targetID == 0 {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Usage: %s <telegram_user_id>", command))
+		return
+	}
+
+	if b.isSuperAdmin(targetID) {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("%d is set in COORDINATOR_IDS. Change the configuration to remove them.", targetID))
+		return
+	}
+
+	changed, err := b.db.SetCoordinator(ctx, targetID, promote, userID)
+	if err != nil {
+		slog.Error("Error changing coordinator role", "user_id", targetID, "promote", promote, "err", err)
+		b.sendMessage(msg.Chat.ID, "Error changing coordinator role. Please try again.")
+		return
+	}
+
+	name := b.volunteerName(ctx, targetID)
+	if !changed {
+		if promote {
+			b.sendMessage(msg.Chat.ID, fmt.Sprintf("%s is already a coordinator.", name))
+		} else {
+			b.sendMessage(msg.Chat.ID, fmt.Sprintf("%s isn't a coordinator.", name))
+		}
+		return
+	}
+
+	b.coordinatorsMutex.Lock()
+	if promote {
+		b.promoted[targetID] = true
+	} else {
+		delete(b.promoted, targetID)
+	}
+	b.coordinatorsMutex.Unlock()
+
+	slog.Info("Coordinator role changed", "user_id", targetID, "promote", promote, "by", userID)
+
+	if promote {
+		b.sendMessage(targetID, "🎖️ You're now a Centromex coordinator. Use /help to see coordinator commands.")
+		b.notifyCoordinators(fmt.Sprintf("🎖️ %s was made a coordinator by %s", name, msg.From.FirstName))
+	} else {
+		b.sendMessage(targetID, "You're no longer a Centromex coordinator. Thank you for your help!")
+		b.notifyCoordinators(fmt.Sprintf("%s is no longer a coordinator (removed by %s)", name, msg.From.FirstName))
+	}
+}
+
+func (b *Bot) handleCoordinators(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can list coordinators.")
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🎖️ COORDINATORS\n")
+	for _, id := range b.coordinators() {
+		sb.WriteString("\n• " + b.volunteerName(ctx, id))
+		if b.isSuperAdmin(id) {
+			sb.WriteString(" (COORDINATOR_IDS)")
+			continue
+		}
+
+		promotion, err := b.db.GetLatestAuditEvent(ctx, id, models.AuditPromote)
+		if err != nil {
+			slog.Error("Error fetching promotion", "user_id", id, "err", err)
+			continue
+		}
+		if promotion != nil {
+			sb.WriteString(fmt.Sprintf(" - promoted by %s on %s",
+				b.volunteerName(ctx, promotion.ActorID), promotion.CreatedAt.Format("Jan 2")))
+		}
+	}
+
+	b.sendMessage(msg.Chat.ID, sb.String())
+}
+
+// volunteerName describes a user by the name they joined with, falling back
+// to their Telegram ID
+func (b *Bot) volunteerName(ctx context.Context, telegramID int64) string {
+	v, err := b.db.GetVolunteer(ctx, telegramID)
+	if err != nil {
+		return strconv.FormatInt(telegramID, 10)
+	}
+	return describeVolunteer(*v)
+}
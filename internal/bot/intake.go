@@ -0,0 +1,252 @@
+package bot
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// flowIntake is a family entering their own request, in Spanish
+const flowIntake = "intake"
+
+// Intake steps, in order. Each step's answer is saved under its name.
+const (
+	stepList    = "list"
+	stepBudget  = "budget"
+	stepZone    = "zone"
+	stepAddress = "address"
+	stepPhone   = "phone"
+	stepWindow  = "window"
+	stepConfirm = "confirm"
+)
+
+var intakeSteps = []string{stepList, stepBudget, stepZone, stepAddress, stepPhone, stepWindow, stepConfirm}
+
+var intakeQuestions = map[string]string{
+	stepList:    "1️⃣ ¿Qué necesita? Escriba su lista de compras con las cantidades.",
+	stepBudget:  "2️⃣ ¿Cuánto puede pagar? (por ejemplo: $80 en efectivo)\nEscriba NO si no sabe.",
+	stepZone:    "3️⃣ ¿En qué barrio o zona vive? (por ejemplo: West Side)\nEscriba NO para saltar.",
+	stepAddress: "4️⃣ ¿Cuál es la dirección para la entrega?\nSolo la verá el voluntario que lleve sus compras.",
+	stepPhone:   "5️⃣ ¿A qué número podemos llamarle?\nEscriba NO para saltar.",
+	stepWindow:  "6️⃣ ¿Qué días y horas puede recibir la entrega? (por ejemplo: martes después de las 5pm)\nEscriba NO para saltar.",
+}
+
+// Steps that can't be skipped with "no"
+var requiredSteps = map[string]bool{stepList: true, stepAddress: true}
+
+// isFamily reports whether a user is neither a coordinator nor a volunteer,
+// so a private chat with them is a family asking for help
+func (b *Bot) isFamily(ctx context.Context, userID int64) bool {
+	if b.isCoordinator(userID) {
+		return false
+	}
+	isVolunteer, err := b.db.IsVolunteer(ctx, userID)
+	if err != nil {
+		slog.Error("Error checking volunteer", "user_id", userID, "err", err)
+		return false
+	}
+	return !isVolunteer
+}
+
+// handleSession continues a conversation in progress in msg's chat and
+// reports whether there was one
+func (b *Bot) handleSession(ctx context.Context, msg *tgbotapi.Message) bool {
+	session, err := b.db.GetSession(ctx, msg.Chat.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		slog.Error("Error loading session", "chat_id", msg.Chat.ID, "err", err)
+		return false
+	}
+
+	switch session.Flow {
+	case flowIntake:
+		b.intakeStep(ctx, msg, session)
+	case flowEdit:
+		b.editStep(ctx, msg, session)
+	default:
+		slog.Warn("Dropping session with unknown flow", "chat_id", msg.Chat.ID, "flow", session.Flow)
+		if err := b.db.DeleteSession(ctx, msg.Chat.ID); err != nil {
+			slog.Error("Error deleting session", "chat_id", msg.Chat.ID, "err", err)
+		}
+		return false
+	}
+	return true
+}
+
+// startIntake begins (or restarts) a family's request
+func (b *Bot) startIntake(ctx context.Context, chatID int64) {
+	session := &models.Session{
+		ChatID: chatID,
+		Flow:   flowIntake,
+		Step:   stepList,
+		Data:   make(map[string]string),
+	}
+	if err := b.db.SaveSession(ctx, session); err != nil {
+		slog.Error("Error starting intake", "chat_id", chatID, "err", err)
+		b.sendMessage(chatID, "Lo sentimos, hubo un error. Por favor intente de nuevo más tarde.")
+		return
+	}
+
+	b.sendMessage(chatID, "¡Hola! Soy el asistente de despensa de Centromex. Le ayudaré a pedir sus compras.\n\n"+
+		"Le haré unas preguntas. En cualquier momento puede escribir CANCELAR para empezar de nuevo.\n\n"+
+		intakeQuestions[stepList])
+}
+
+func (b *Bot) cancelIntake(ctx context.Context, chatID int64) {
+	if err := b.db.DeleteSession(ctx, chatID); err != nil {
+		slog.Error("Error cancelling intake", "chat_id", chatID, "err", err)
+	}
+	b.sendMessage(chatID, "Pedido cancelado. Escriba /start cuando quiera empezar de nuevo.")
+}
+
+// intakeStep saves the answer to the current question and asks the next
+func (b *Bot) intakeStep(ctx context.Context, msg *tgbotapi.Message, session *models.Session) {
+	answer := strings.TrimSpace(msg.Text)
+	lower := strings.ToLower(answer)
+
+	if lower == "cancelar" {
+		b.cancelIntake(ctx, msg.Chat.ID)
+		return
+	}
+	if answer == "" {
+		b.sendMessage(msg.Chat.ID, "Por favor responda con un mensaje de texto.\n\n"+b.intakeQuestion(session))
+		return
+	}
+
+	if session.Step == stepConfirm {
+		switch lower {
+		case "si", "sí", "yes", "ok":
+			b.submitIntake(ctx, msg, session)
+		default:
+			b.sendMessage(msg.Chat.ID, "Escriba SÍ para enviar su pedido o CANCELAR para empezar de nuevo.")
+		}
+		return
+	}
+
+	if lower == "no" || lower == "saltar" || lower == "-" {
+		if requiredSteps[session.Step] {
+			b.sendMessage(msg.Chat.ID, "Necesitamos esta información para su pedido.\n\n"+intakeQuestions[session.Step])
+			return
+		}
+		answer = ""
+	}
+	session.Data[session.Step] = answer
+
+	for i, step := range intakeSteps {
+		if step == session.Step {
+			session.Step = intakeSteps[i+1]
+			break
+		}
+	}
+
+	if err := b.db.SaveSession(ctx, session); err != nil {
+		slog.Error("Error saving intake", "chat_id", msg.Chat.ID, "step", session.Step, "err", err)
+		b.sendMessage(msg.Chat.ID, "Lo sentimos, hubo un error. Por favor envíe su respuesta otra vez.")
+		return
+	}
+
+	b.sendMessage(msg.Chat.ID, b.intakeQuestion(session))
+}
+
+// intakeQuestion is the prompt for the session's current step
+func (b *Bot) intakeQuestion(session *models.Session) string {
+	if session.Step != stepConfirm {
+		return intakeQuestions[session.Step]
+	}
+
+	orNone := func(value string) string {
+		if value == "" {
+			return "—"
+		}
+		return value
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Revise su pedido:\n\n")
+	sb.WriteString("🛒 Lista:\n" + session.Data[stepList] + "\n\n")
+	sb.WriteString("💵 Presupuesto: " + orNone(session.Data[stepBudget]) + "\n")
+	sb.WriteString("📍 Zona: " + orNone(session.Data[stepZone]) + "\n")
+	sb.WriteString("🏠 Dirección: " + session.Data[stepAddress] + "\n")
+	sb.WriteString("📞 Teléfono: " + orNone(session.Data[stepPhone]) + "\n")
+	sb.WriteString("🕐 Entrega: " + orNone(session.Data[stepWindow]) + "\n\n")
+	sb.WriteString("¿Está todo correcto? Escriba SÍ para enviar o CANCELAR para empezar de nuevo.")
+	return sb.String()
+}
+
+// submitIntake creates the family's request and holds it for a
+// coordinator to review before it is posted to volunteers
+func (b *Bot) submitIntake(ctx context.Context, msg *tgbotapi.Message, session *models.Session) {
+	chatID := msg.Chat.ID
+	data := session.Data
+
+	budget := data[stepBudget]
+	if budget == "" {
+		budget = extractBudget(data[stepList])
+	}
+
+	req, err := b.db.CreateRequest(ctx, data[stepList], budget, data[stepZone], 0)
+	if err != nil {
+		slog.Error("Error creating request from intake", "chat_id", chatID, "err", err)
+		b.sendMessage(chatID, "Lo sentimos, hubo un error. Por favor escriba SÍ otra vez en unos minutos.")
+		return
+	}
+
+	if err := b.db.DeleteSession(ctx, chatID); err != nil {
+		slog.Error("Error deleting intake session", "chat_id", chatID, "err", err)
+	}
+
+	if err := b.db.SaveContact(ctx, req.ID, data[stepAddress], data[stepPhone]); err != nil {
+		slog.Error("Error saving contact", "request_id", req.ID, "err", err)
+	}
+	if err := b.db.LinkFamilyChat(ctx, req.ID, chatID); err != nil {
+		slog.Error("Error linking family chat", "request_id", req.ID, "err", err)
+	}
+	if data[stepWindow] != "" {
+		if err := b.db.UpdateRequestDeliveryWindow(ctx, req.ID, data[stepWindow]); err != nil {
+			slog.Error("Error saving delivery window", "request_id", req.ID, "err", err)
+		}
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("✅ ¡Gracias! Recibimos su pedido #%d. Un coordinador lo revisará pronto y le avisaremos cuando un voluntario lo tome.", req.ID))
+
+	// Translate now so coordinators review the English list volunteers will see
+	result, needsTranslation := b.translate(ctx, req.ID, data[stepList])
+
+	if budget == "" && result.Budget != "" {
+		if err := b.db.UpdateRequestBudget(ctx, req.ID, result.Budget); err != nil {
+			slog.Error("Error saving budget", "request_id", req.ID, "err", err)
+		}
+	}
+	if data[stepPhone] == "" && result.Phone != "" {
+		if err := b.db.SaveContact(ctx, req.ID, "", result.Phone); err != nil {
+			slog.Error("Error saving extracted phone", "request_id", req.ID, "err", err)
+		}
+	}
+
+	err = b.db.UpdateRequestTranslation(ctx, req.ID, result.CleanedText, result.Items, result.Notes, models.StatusPendingReview, needsTranslation)
+	if err != nil {
+		slog.Error("Error saving translation", "request_id", req.ID, "err", err)
+		b.notifyCoordinators(fmt.Sprintf("⚠️ A family submitted request #%d but it couldn't be saved for review: %v", req.ID, err))
+		return
+	}
+
+	b.requestReview(ctx, req.ID, "📥 NEW REQUEST FROM A FAMILY")
+}
+
+// tellFamily messages the family directly if their Telegram chat is linked
+func (b *Bot) tellFamily(ctx context.Context, requestID int64, spanishText string) {
+	contact, err := b.db.GetContact(ctx, requestID)
+	if err != nil || contact.ChatID == 0 {
+		return
+	}
+	b.sendMessage(contact.ChatID, spanishText)
+}
@@ -0,0 +1,45 @@
+package bot
+
+import "sync"
+
+// requestLocks serializes the handlers that change a request. Updates from
+// different users are processed in parallel, so without it a coordinator's
+// /address could interleave with a volunteer's /done. The database checks
+// every transition as well; the lock also keeps the card edits and
+// messages that follow a change in order.
+type requestLocks struct {
+	mu    sync.Mutex
+	locks map[int64]*requestLock
+}
+
+type requestLock struct {
+	sync.Mutex
+	refs int // Handlers holding or waiting for the lock
+}
+
+// lock blocks until no other handler holds requestID's lock and returns
+// the function that releases it
+func (l *requestLocks) lock(requestID int64) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[int64]*requestLock)
+	}
+	rl := l.locks[requestID]
+	if rl == nil {
+		rl = &requestLock{}
+		l.locks[requestID] = rl
+	}
+	rl.refs++
+	l.mu.Unlock()
+
+	rl.Lock()
+	return func() {
+		rl.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if rl.refs--; rl.refs == 0 {
+			delete(l.locks, requestID)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRequestLocks(t *testing.T) {
+	var locks requestLocks
+	var wg sync.WaitGroup
+	holders := make(map[int64]int)
+	var mu sync.Mutex
+	counts := make([]int, 4) // Only touched under each request's lock
+
+	for i := range 200 {
+		requestID := int64(i % 4)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := locks.lock(requestID)
+			defer unlock()
+
+			mu.Lock()
+			holders[requestID]++
+			if holders[requestID] > 1 {
+				t.Errorf("request %d held by %d handlers at once", requestID, holders[requestID])
+			}
+			mu.Unlock()
+
+			counts[requestID]++
+
+			mu.Lock()
+			holders[requestID]--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for id, n := range counts {
+		if n != 50 {
+			t.Errorf("request %d handled %d times, want 50", id, n)
+		}
+	}
+	if len(locks.locks) != 0 {
+		t.Errorf("%d locks left after every handler finished", len(locks.locks))
+	}
+}
@@ -0,0 +1,32 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/centromex/grocery-bot/internal/pii"
+)
+
+// scrubForChat redacts personal information from anything headed for the
+// volunteer chat and flags what it caught to coordinators, since it means
+// the translator let something through. Other chats get text unchanged.
+func (b *Bot) scrubForChat(chatID int64, text string) string {
+	if chatID != b.volunteerChat {
+		return text
+	}
+
+	scrubbed, findings := pii.Scrub(text)
+	if len(findings) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🛡️ Personal information was removed from a message to the volunteer chat:\n")
+	for _, f := range findings {
+		sb.WriteString(fmt.Sprintf("\n• %s: %s", f.Kind, f.Text))
+	}
+	sb.WriteString("\n\nVolunteers saw:\n" + scrubbed)
+	b.notifyCoordinators(sb.String())
+
+	return scrubbed
+}
@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Updates are saved to the database before they are processed and marked
+// finished after, so one that was in progress when the bot stopped or
+// crashed is replayed at the next start.
+
+const (
+	defaultWorkers = 4
+
+	// Queued updates per worker before enqueue waits
+	queueSize = 64
+
+	// maxUpdateAttempts is how often an update is started before it's given
+	// up on. Attempts only repeat when the bot died while processing it.
+	maxUpdateAttempts = 3
+)
+
+// enqueue saves an update and hands it to a worker, dropping duplicates.
+// An error means the update wasn't saved and Telegram should resend it.
+func (b *Bot) enqueue(ctx context.Context, update tgbotapi.Update) error {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("failed to encode update: %w", err)
+	}
+
+	isNew, err := b.db.EnqueueUpdate(ctx, update.UpdateID, payload)
+	if err != nil {
+		return fmt.Errorf("failed to queue update: %w", err)
+	}
+	if !isNew {
+		slog.Debug("Skipping duplicate update", "update_id", update.UpdateID)
+		return nil
+	}
+
+	b.dispatch(update)
+	return nil
+}
+
+// dispatch sends an update to its worker. Each user's updates always go to
+// the same worker, so a family's intake answers are handled in order. Once
+// the bot is stopping the update is left in the database for the next start
+// instead of waiting on a queue that may no longer be worked.
+func (b *Bot) dispatch(update tgbotapi.Update) {
+	var userID int64
+	switch {
+	case update.Message != nil && update.Message.From != nil:
+		userID = update.Message.From.ID
+	case update.CallbackQuery != nil:
+		userID = update.CallbackQuery.From.ID
+	}
+	if userID < 0 {
+		userID = -userID
+	}
+	select {
+	case b.queues[userID%int64(len(b.queues))] <- update:
+	case <-b.stopping:
+		slog.Info("Stopping, leaving update for the next start", "update_id", update.UpdateID)
+	}
+}
+
+// startWorkers starts one goroutine per queue, processing updates with ctx
+// until the bot is stopping and the queue is empty
+func (b *Bot) startWorkers(ctx context.Context) {
+	for _, queue := range b.queues {
+		b.workers.Add(1)
+		go func() {
+			defer b.workers.Done()
+			for {
+				select {
+				case update := <-queue:
+					b.work(ctx, update)
+				case <-b.stopping:
+					b.finishQueue(ctx, queue)
+					return
+				}
+			}
+		}()
+	}
+}
+
+// finishQueue works through what is left in a queue after stopping. An
+// update a handler slips in afterwards may not be worked, but it's saved
+// and replayed at the next start.
+func (b *Bot) finishQueue(ctx context.Context, queue chan tgbotapi.Update) {
+	for {
+		select {
+		case update := <-queue:
+			b.work(ctx, update)
+		default:
+			return
+		}
+	}
+}
+
+// stopWorkers tells the workers to finish what is queued and exit, and any
+// webhook handler still waiting to queue an update to give up on it. The
+// queues are never closed, so a handler outliving the server's shutdown
+// can't send on a closed channel.
+func (b *Bot) stopWorkers() {
+	close(b.stopping)
+}
+
+// work processes one queued update and marks it finished. If ctx is
+// cancelled first, or processing panics, the update stays pending and is
+// replayed at the next start.
+func (b *Bot) work(ctx context.Context, update tgbotapi.Update) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	if err := b.db.StartUpdate(ctx, update.UpdateID); err != nil {
+		slog.Error("Error recording update attempt", "update_id", update.UpdateID, "err", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Panic processing update", "update_id", update.UpdateID, "panic", r)
+			return
+		}
+		// Recorded even if shutdown cancelled ctx meanwhile, since the
+		// handler has run and replaying it would repeat its changes
+		if err := b.db.FinishUpdate(context.WithoutCancel(ctx), update.UpdateID); err != nil {
+			slog.Error("Error finishing update", "update_id", update.UpdateID, "err", err)
+		}
+	}()
+
+	b.processUpdate(ctx, update)
+}
+
+// replayPending queues updates left unfinished by the last run
+func (b *Bot) replayPending(ctx context.Context) {
+	pending, err := b.db.PendingUpdates(ctx)
+	if err != nil {
+		slog.Error("Error loading unfinished updates", "err", err)
+		return
+	}
+
+	for _, queued := range pending {
+		if queued.Attempts >= maxUpdateAttempts {
+			slog.Error("Giving up on update that failed repeatedly", "update_id", queued.UpdateID, "attempts", queued.Attempts)
+			b.failUpdate(ctx, queued.UpdateID)
+			continue
+		}
+
+		var update tgbotapi.Update
+		if err := json.Unmarshal(queued.Payload, &update); err != nil {
+			slog.Error("Error decoding queued update", "update_id", queued.UpdateID, "err", err)
+			b.failUpdate(ctx, queued.UpdateID)
+			continue
+		}
+
+		slog.Info("Replaying unfinished update", "update_id", queued.UpdateID, "attempts", queued.Attempts)
+		b.dispatch(update)
+	}
+}
+
+func (b *Bot) failUpdate(ctx context.Context, updateID int) {
+	if err := b.db.FailUpdate(ctx, updateID); err != nil {
+		slog.Error("Error dropping update", "update_id", updateID, "err", err)
+	}
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/db"
+)
+
+func TestStopWhileDispatching(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "bot.db"), "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	b := &Bot{
+		db:       database,
+		queues:   []chan tgbotapi.Update{make(chan tgbotapi.Update, 1)},
+		stopping: make(chan struct{}),
+	}
+	ctx := context.Background()
+
+	// Fill the queue so the next update's handler waits in dispatch, like a
+	// webhook handler still running when the server's shutdown times out
+	if err := b.enqueue(ctx, tgbotapi.Update{UpdateID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	blocked := make(chan error)
+	go func() {
+		blocked <- b.enqueue(ctx, tgbotapi.Update{UpdateID: 2})
+	}()
+
+	b.stopWorkers()
+	select {
+	case err := <-blocked:
+		if err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("enqueue still waiting on a full queue after stopping")
+	}
+
+	// Updates arriving after that are saved without panicking
+	if err := b.enqueue(ctx, tgbotapi.Update{UpdateID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Workers finish what was queued before stopping, then exit
+	b.startWorkers(ctx)
+	b.workers.Wait()
+
+	pending, err := database.PendingUpdates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, update := range pending {
+		ids = append(ids, update.UpdateID)
+	}
+	if want := []int{2, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("pending updates = %v, want %v left for the next start", ids, want)
+	}
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+func (b *Bot) handleTell(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	idStr, text, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	requestID, err := parseID(idStr)
+	text = strings.TrimSpace(text)
+	if err != nil || text == "" {
+		b.sendMessage(msg.Chat.ID, "Usage: /tell <request_id> <message in English>\nExample: /tell 42 I'm 10 minutes away")
+		return
+	}
+
+	if err := b.tell(ctx, msg.Chat.ID, userID, requestID, text); err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not send message for request #%d: %s", requestID, err.Error()))
+	}
+}
+
+// tell translates a volunteer's message into Spanish and relays it to the
+// family: directly if their Telegram chat is linked, otherwise through the
+// coordinator in touch with them. The family never sees who sent it.
+func (b *Bot) tell(ctx context.Context, chatID int64, userID int64, requestID int64, text string) error {
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		return fmt.Errorf("request not found")
+	}
+	isVolunteer := req.ClaimedBy == userID && (req.Status == models.StatusClaimed || req.Status == models.StatusShopping)
+	if !isVolunteer && !b.isCoordinator(userID) {
+		return fmt.Errorf("you don't have this request claimed")
+	}
+
+	spanish, err := b.translator.TranslateToSpanish(ctx, text)
+	if err != nil {
+		slog.Error("Error translating message for family", "request_id", requestID, "user_id", userID, "err", err)
+	}
+
+	var familyChat int64
+	if contact, err := b.db.GetContact(ctx, requestID); err == nil {
+		familyChat = contact.ChatID
+	}
+
+	if familyChat != 0 && spanish != "" {
+		b.sendMessage(familyChat, "💬 Mensaje de su voluntario(a) de Centromex:\n\n"+spanish)
+		b.sendMessage(chatID, fmt.Sprintf("📨 Sent to the family on request #%d:\n\n🇪🇸 %s", requestID, spanish))
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("📨 Message from the volunteer for the family on request #%d\n\n", requestID))
+	if spanish != "" {
+		sb.WriteString("🇪🇸 Please forward:\n" + spanish + "\n\n")
+		sb.WriteString("🇺🇸 English: " + text)
+	} else {
+		sb.WriteString("⚠️ Automatic translation failed, please translate:\n" + text)
+	}
+
+	for _, coordID := range b.familyContacts(req) {
+		b.sendMessage(coordID, sb.String())
+	}
+	b.sendMessage(chatID, "📨 Sent to the coordinator to pass on to the family.")
+	return nil
+}
+
+// handleLinkFamily lets a coordinator link a family's own Telegram account
+// to a request so /tell messages reach them directly
+func (b *Bot) handleLinkFamily(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can link families.")
+		return
+	}
+
+	// Only allow via DM to protect family info
+	if msg.Chat.ID != userID {
+		b.sendMessage(msg.Chat.ID, "⚠️ Please send /linkfamily via DM to protect family information.")
+		return
+	}
+
+	args := strings.Fields(msg.CommandArguments())
+	if len(args) != 2 {
+		b.sendMessage(msg.Chat.ID, "Usage: /linkfamily <request_id> <family's Telegram user ID>")
+		return
+	}
+	requestID, err := parseID(args[0])
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Invalid request ID.")
+		return
+	}
+	familyChat, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || familyChat == 0 {
+		b.sendMessage(msg.Chat.ID, "Invalid Telegram user ID.")
+		return
+	}
+
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	if _, err := b.db.GetRequest(ctx, requestID); err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Request #%d not found.", requestID))
+		return
+	}
+
+	if err := b.db.LinkFamilyChat(ctx, requestID, familyChat); err != nil {
+		b.sendMessage(msg.Chat.ID, "Error linking family. Please try again.")
+		slog.Error("Error linking family chat", "request_id", requestID, "err", err)
+		return
+	}
+
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Family linked to request #%d. Volunteer messages will go to them directly.", requestID))
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// flowEdit is a coordinator sending corrected text for a request under review
+const flowEdit = "edit"
+
+// requestReview DMs a pending request's card to the coordinator in touch
+// with the family (or all coordinators) with Approve / Edit / Reject
+// buttons. Nothing reaches the volunteer chat until it is approved.
+func (b *Bot) requestReview(ctx context.Context, requestID int64, heading string) {
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		slog.Error("Error fetching request for review", "request_id", requestID, "err", err)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(heading + "\n\n")
+	sb.WriteString(b.translator.FormatRequest(req.ID, req.Zone, req.Budget, shoppingList(req)))
+	if req.DeliveryWindow != "" {
+		sb.WriteString("\n🕐 Delivery: " + req.DeliveryWindow)
+	}
+	sb.WriteString("\n\n🇪🇸 Original:\n" + req.OriginalText)
+
+	for _, coordID := range b.familyContacts(req) {
+		b.sendWithKeyboard(coordID, sb.String(), reviewKeyboard(requestID))
+	}
+}
+
+// approveRequest posts a reviewed request to the volunteer chat
+func (b *Bot) approveRequest(ctx context.Context, userID int64, requestID int64) error {
+	if !b.isCoordinator(userID) {
+		return fmt.Errorf("only coordinators can approve requests")
+	}
+
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	if err := b.db.ApproveRequest(ctx, requestID); err != nil {
+		return err
+	}
+
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	b.postCard(ctx, req.ID, req.Zone, req.Budget, shoppingList(req))
+
+	b.tellFamily(ctx, requestID, fmt.Sprintf("✅ Su pedido #%d fue aprobado y enviado a nuestros voluntarios.", requestID))
+	b.notifyCoordinators(fmt.Sprintf("📢 Request #%d approved and posted", requestID))
+	return nil
+}
+
+// rejectRequest cancels a request that didn't pass review
+func (b *Bot) rejectRequest(ctx context.Context, userID int64, requestID int64) error {
+	if !b.isCoordinator(userID) {
+		return fmt.Errorf("only coordinators can reject requests")
+	}
+
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		return fmt.Errorf("request not found")
+	}
+	if req.Status != models.StatusPendingReview {
+		return fmt.Errorf("request is %s, not waiting for review", req.Status)
+	}
+
+	// Tell the family before their contact details are deleted
+	b.tellFamily(ctx, requestID, fmt.Sprintf("Lo sentimos, no pudimos aceptar su pedido #%d. Un coordinador se comunicará con usted.", requestID))
+
+	if err := b.db.CancelRequest(ctx, requestID); err != nil {
+		return err
+	}
+
+	b.notifyCoordinators(fmt.Sprintf("❌ Request #%d rejected", requestID))
+	return nil
+}
+
+// startEdit asks a coordinator for corrected text for a request under
+// review. Their next message in the DM replaces the translation.
+func (b *Bot) startEdit(ctx context.Context, userID int64, requestID int64) error {
+	if !b.isCoordinator(userID) {
+		return fmt.Errorf("only coordinators can edit requests")
+	}
+
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		return fmt.Errorf("request not found")
+	}
+	if req.Status != models.StatusPendingReview {
+		return fmt.Errorf("request is %s, not waiting for review", req.Status)
+	}
+
+	session := &models.Session{
+		ChatID: userID,
+		Flow:   flowEdit,
+		Step:   "text",
+		Data:   map[string]string{"request_id": strconv.FormatInt(requestID, 10)},
+	}
+	if err := b.db.SaveSession(ctx, session); err != nil {
+		return err
+	}
+
+	b.sendMessage(userID, fmt.Sprintf("✏️ Send the corrected English list for request #%d as your next message. "+
+		"It replaces the translation shown on the card.\n\nCurrent text to copy:\n\n%s\n\nSend CANCEL to keep it as is.",
+		requestID, shoppingList(req)))
+	return nil
+}
+
+// editStep replaces a request's translation with the coordinator's text
+// and sends it back for review
+func (b *Bot) editStep(ctx context.Context, msg *tgbotapi.Message, session *models.Session) {
+	requestID, err := strconv.ParseInt(session.Data["request_id"], 10, 64)
+	if err != nil {
+		slog.Warn("Invalid edit session", "chat_id", msg.Chat.ID, "err", err)
+		if err := b.db.DeleteSession(ctx, msg.Chat.ID); err != nil {
+			slog.Error("Error ending edit session", "chat_id", msg.Chat.ID, "err", err)
+		}
+		return
+	}
+
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		b.sendMessage(msg.Chat.ID, "Please send the corrected list as text, or CANCEL.")
+		return
+	}
+
+	if err := b.db.DeleteSession(ctx, msg.Chat.ID); err != nil {
+		slog.Error("Error ending edit session", "chat_id", msg.Chat.ID, "err", err)
+	}
+
+	if strings.EqualFold(text, "cancel") {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Edit cancelled. Request #%d is still waiting for review.", requestID))
+		return
+	}
+
+	unlock := b.requestLocks.lock(requestID)
+	defer unlock()
+
+	if err := b.db.ReplaceTranslation(ctx, requestID, text); err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not update request #%d: %s", requestID, err.Error()))
+		return
+	}
+
+	b.requestReview(ctx, requestID, "✏️ EDITED - please review again")
+}
@@ -0,0 +1,195 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/db"
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+const subUsage = "Usage: /sub <request_id> <item> -> <replacement>\nExample: /sub 42 queso fresco -> queso blanco"
+
+func (b *Bot) handleSub(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	idStr, rest, _ := strings.Cut(strings.TrimSpace(msg.CommandArguments()), " ")
+	requestID, err := parseID(idStr)
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, subUsage)
+		return
+	}
+
+	item, replacement, ok := strings.Cut(strings.ReplaceAll(rest, "→", "->"), "->")
+	item, replacement = strings.TrimSpace(item), strings.TrimSpace(replacement)
+	if !ok || item == "" || replacement == "" {
+		b.sendMessage(msg.Chat.ID, subUsage)
+		return
+	}
+
+	if err := b.proposeSubstitution(ctx, msg.Chat.ID, msg.From, requestID, item, replacement); err != nil {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("Could not propose substitution for request #%d: %s", requestID, err.Error()))
+	}
+}
+
+// proposeSubstitution records a substitution and asks the coordinator in
+// touch with the family to check it with them, in Spanish.
+func (b *Bot) proposeSubstitution(ctx context.Context, chatID int64, from *tgbotapi.User, requestID int64, item, replacement string) error {
+	req, err := b.db.GetRequest(ctx, requestID)
+	if err != nil {
+		return fmt.Errorf("request not found")
+	}
+
+	sub := &models.Substitution{
+		RequestID:      requestID,
+		Item:           item,
+		Replacement:    replacement,
+		ProposedBy:     from.ID,
+		ProposedByName: from.FirstName,
+	}
+	if matched := matchItem(req.Items, item); matched != nil {
+		sub.ItemID = matched.ID
+		sub.Item = matched.Name
+	}
+
+	sub, err = b.db.CreateSubstitution(ctx, sub)
+	if err != nil {
+		return err
+	}
+
+	question := fmt.Sprintf("The store doesn't have %s. Would %s be OK instead?", sub.Item, sub.Replacement)
+	spanish, err := b.translator.TranslateToSpanish(ctx, question)
+	if err != nil {
+		slog.Error("Error translating substitution", "request_id", requestID, "substitution_id", sub.ID, "err", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔄 SUBSTITUTION for request #%d\n\n", requestID))
+	sb.WriteString(fmt.Sprintf("%s can't find: %s\n", sub.ProposedByName, sub.Item))
+	sb.WriteString(fmt.Sprintf("Proposes: %s\n\n", sub.Replacement))
+	if spanish != "" {
+		sb.WriteString("🇪🇸 Ask the family:\n" + spanish + "\n\n")
+	} else {
+		sb.WriteString("Ask the family:\n" + question + "\n\n")
+	}
+	sb.WriteString("Tap their answer below.")
+
+	for _, coordID := range b.familyContacts(req) {
+		b.sendWithKeyboard(coordID, sb.String(), substitutionKeyboard(sub.ID))
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("🔄 Asking the family about %s instead of %s. I'll message you when they answer.", sub.Replacement, sub.Item))
+	return nil
+}
+
+// decideSubstitution handles a coordinator's approve/reject button and
+// relays the family's answer to the volunteer. Like toggleItem it answers
+// the callback itself, since the button carries a substitution ID.
+func (b *Bot) decideSubstitution(ctx context.Context, cq *tgbotapi.CallbackQuery, subID int64, approved bool) {
+	if !b.isCoordinator(cq.From.ID) {
+		b.answerCallback(cq.ID, "Only coordinators can answer substitutions.", true)
+		return
+	}
+
+	pending, err := b.db.GetSubstitution(ctx, subID)
+	if err != nil {
+		b.answerCallback(cq.ID, err.Error(), true)
+		return
+	}
+
+	unlock := b.requestLocks.lock(pending.RequestID)
+	defer unlock()
+
+	sub, err := b.db.DecideSubstitution(ctx, subID, cq.From.ID, approved)
+	if errors.Is(err, db.ErrSubstitutionStale) {
+		// Take the buttons away so nobody answers it again
+		b.answerCallback(cq.ID, err.Error(), true)
+		if cq.Message != nil {
+			edit := tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, cq.Message.Text+"\n\n⚠️ No longer applies - the request was released or finished")
+			if _, err := b.api.Send(edit); err != nil {
+				slog.Error("Error editing substitution", "request_id", pending.RequestID, "substitution_id", subID, "err", err)
+			}
+		}
+		return
+	}
+	if err != nil {
+		b.answerCallback(cq.ID, err.Error(), true)
+		return
+	}
+
+	result := fmt.Sprintf("❌ Family said no to %s", sub.Replacement)
+	volunteerText := fmt.Sprintf("❌ Request #%d: the family said no to %s instead of %s. Please skip it or propose something else with /sub.",
+		sub.RequestID, sub.Replacement, sub.Item)
+	if approved {
+		result = fmt.Sprintf("✅ Family approved %s", sub.Replacement)
+		volunteerText = fmt.Sprintf("✅ Request #%d: the family says %s instead of %s is OK!",
+			sub.RequestID, sub.Replacement, sub.Item)
+	}
+
+	b.sendMessage(sub.ProposedBy, volunteerText)
+	b.answerCallback(cq.ID, result, false)
+
+	if cq.Message != nil {
+		edit := tgbotapi.NewEditMessageText(cq.Message.Chat.ID, cq.Message.MessageID, cq.Message.Text+"\n\n"+result)
+		if _, err := b.api.Send(edit); err != nil {
+			slog.Error("Error editing substitution", "request_id", sub.RequestID, "substitution_id", sub.ID, "err", err)
+		}
+	}
+}
+
+// familyContacts returns who to ask about a request: the coordinator who
+// entered it, or every coordinator if that's unknown.
+func (b *Bot) familyContacts(req *models.Request) []int64 {
+	if req.CreatedBy != 0 {
+		return []int64{req.CreatedBy}
+	}
+	return b.coordinators()
+}
+
+// substitutionSummary lists a request's substitutions for receipts, or
+// returns "" if there are none
+func (b *Bot) substitutionSummary(ctx context.Context, requestID int64) string {
+	subs, err := b.db.GetSubstitutions(ctx, requestID)
+	if err != nil {
+		slog.Error("Error fetching substitutions", "request_id", requestID, "err", err)
+		return ""
+	}
+	if len(subs) == 0 {
+		return ""
+	}
+
+	icons := map[models.SubstitutionStatus]string{
+		models.SubstitutionPending:  "⏳",
+		models.SubstitutionApproved: "✅",
+		models.SubstitutionRejected: "❌",
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🔄 Substitutions:")
+	for _, sub := range subs {
+		sb.WriteString(fmt.Sprintf("\n%s %s → %s", icons[sub.Status], sub.Item, sub.Replacement))
+	}
+	return sb.String()
+}
+
+// matchItem finds the request item a volunteer is referring to by its
+// English or Spanish name, or returns nil
+func matchItem(items []models.RequestItem, name string) *models.RequestItem {
+	for i := range items {
+		if strings.EqualFold(items[i].Name, name) || strings.EqualFold(items[i].Original, name) {
+			return &items[i]
+		}
+	}
+
+	name = strings.ToLower(name)
+	for i := range items {
+		if strings.Contains(strings.ToLower(items[i].Name), name) ||
+			(items[i].Original != "" && strings.Contains(strings.ToLower(items[i].Original), name)) {
+			return &items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/centromex/grocery-bot/internal/models"
+	"github.com/centromex/grocery-bot/internal/translator"
+)
+
+// needsTranslationBanner heads a list that is still the family's Spanish
+const needsTranslationBanner = "⚠️ NEEDS TRANSLATION - automatic translation failed, original Spanish below:\n\n"
+
+// translate runs the translator's fallback chain. When every backend fails
+// the result is empty and the second result reports that it needs
+// translating: the list is then shown from the family's original Spanish,
+// which stays encrypted at rest, and the volunteer chat's PII scrubber
+// still applies to it.
+func (b *Bot) translate(ctx context.Context, requestID int64, spanishText string) (*translator.TranslationResult, bool) {
+	result, err := b.translator.TranslateRequest(ctx, spanishText)
+	if err == nil {
+		return result, false
+	}
+	slog.Warn("Translation failed, falling back to the original Spanish", "request_id", requestID, "err", err)
+	return &translator.TranslationResult{}, true
+}
+
+// RetryTranslations retries requests whose translation never finished:
+// ones stuck in new for longer than stuckFor are translated and posted as
+// if just created, and open ones still showing the family's Spanish get
+// the English list once a translator answers.
+func (b *Bot) RetryTranslations(ctx context.Context, stuckFor time.Duration) {
+	ids, err := b.db.GetUntranslatedRequests(ctx, stuckFor)
+	if err != nil {
+		slog.Error("Error fetching untranslated requests", "err", err)
+		return
+	}
+
+	for _, id := range ids {
+		req, err := b.db.GetRequest(ctx, id)
+		if err != nil {
+			slog.Error("Error fetching request for translation retry", "request_id", id, "err", err)
+			continue
+		}
+		if req.Status == models.StatusNew {
+			b.retryStuckRequest(ctx, req)
+		} else {
+			b.retryTranslation(ctx, req)
+		}
+	}
+}
+
+// retryStuckRequest finishes a request left in new, e.g. by a restart in
+// the middle of createRequest
+func (b *Bot) retryStuckRequest(ctx context.Context, req *models.Request) {
+	result, needsTranslation := b.translate(ctx, req.ID, req.OriginalText)
+
+	// Not held while translating, which can take minutes
+	unlock := b.requestLocks.lock(req.ID)
+	defer unlock()
+
+	// Requests without a coordinator came from a family's own intake
+	next := models.StatusPosted
+	if b.reviewBeforePosting || req.CreatedBy == 0 {
+		next = models.StatusPendingReview
+	}
+	err := b.db.UpdateRequestTranslation(ctx, req.ID, result.CleanedText, result.Items, result.Notes, next, needsTranslation)
+	if err != nil {
+		slog.Error("Error saving retried translation", "request_id", req.ID, "err", err)
+		return
+	}
+
+	if req.Budget == "" && result.Budget != "" {
+		if err := b.db.UpdateRequestBudget(ctx, req.ID, result.Budget); err != nil {
+			slog.Error("Error saving budget", "request_id", req.ID, "err", err)
+		}
+	}
+	if result.Address != "" || result.Phone != "" {
+		if err := b.db.SaveContact(ctx, req.ID, result.Address, result.Phone); err != nil {
+			slog.Error("Error saving extracted contact", "request_id", req.ID, "err", err)
+		}
+	}
+
+	slog.Info("Finished stuck request", "request_id", req.ID, "status", next)
+
+	if next == models.StatusPendingReview {
+		b.requestReview(ctx, req.ID, "🔁 DELAYED REQUEST - translation was retried, please review")
+		return
+	}
+
+	id := req.ID
+	posted, err := b.db.GetRequest(ctx, id)
+	if err != nil {
+		slog.Error("Error fetching request to post", "request_id", id, "err", err)
+		return
+	}
+	b.postCard(ctx, id, posted.Zone, posted.Budget, shoppingList(posted))
+	b.notifyCoordinators(fmt.Sprintf("🔁 Request #%d was stuck waiting for translation and has now been posted", id))
+}
+
+// retryTranslation swaps the Spanish fallback of an open request for an
+// English translation, leaving it as it is if translation still fails
+func (b *Bot) retryTranslation(ctx context.Context, req *models.Request) {
+	result, err := b.translator.TranslateRequest(ctx, req.OriginalText)
+	if err != nil {
+		slog.Warn("Translation still failing", "request_id", req.ID, "err", err)
+		return
+	}
+
+	unlock := b.requestLocks.lock(req.ID)
+	defer unlock()
+
+	if err := b.db.RetranslateRequest(ctx, req.ID, result.CleanedText, result.Items, result.Notes); err != nil {
+		slog.Error("Error saving retried translation", "request_id", req.ID, "err", err)
+		return
+	}
+	slog.Info("Translated on retry", "request_id", req.ID)
+	if req.Budget == "" && result.Budget != "" {
+		if err := b.db.UpdateRequestBudget(ctx, req.ID, result.Budget); err != nil {
+			slog.Error("Error saving budget", "request_id", req.ID, "err", err)
+		}
+	}
+
+	if req.Status == models.StatusPendingReview {
+		b.requestReview(ctx, req.ID, "🌐 TRANSLATION NOW AVAILABLE - please review this instead")
+		return
+	}
+	b.updateCard(ctx, req.ID)
+}
@@ -0,0 +1,338 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/centromex/grocery-bot/internal/db"
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// parseUserArgs splits a volunteer command's arguments into the target's
+// Telegram user ID and any text after it
+func parseUserArgs(args string) (int64, string, error) {
+	idStr, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id == 0 {
+		return 0, "", fmt.Errorf("invalid user ID")
+	}
+	return id, strings.TrimSpace(rest), nil
+}
+
+func (b *Bot) handleApprove(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can approve volunteers.")
+		return
+	}
+
+	volunteerID, _, err := parseUserArgs(msg.CommandArguments())
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Usage: /approve <telegram_user_id>")
+		return
+	}
+
+	approved, err := b.db.ApproveVolunteer(ctx, volunteerID, userID)
+	if errors.Is(err, db.ErrVolunteerBanned) {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("%s is banned. Use /unban %d first.", b.volunteerName(ctx, volunteerID), volunteerID))
+		return
+	}
+	if err != nil {
+		slog.Error("Error approving volunteer", "user_id", volunteerID, "err", err)
+		b.sendMessage(msg.Chat.ID, "Error approving volunteer.")
+		return
+	}
+
+	name := b.volunteerName(ctx, volunteerID)
+	if !approved {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("%s is already approved.", name))
+		return
+	}
+
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Volunteer %s approved.", name))
+	b.sendMessage(volunteerID, "✅ You're approved as a Centromex volunteer! Use /list to see open requests.")
+}
+
+func (b *Bot) handleRevoke(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can revoke volunteers.")
+		return
+	}
+
+	volunteerID, _, err := parseUserArgs(msg.CommandArguments())
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Usage: /revoke <telegram_user_id>")
+		return
+	}
+	if b.isCoordinator(volunteerID) {
+		b.sendMessage(msg.Chat.ID, "Coordinators can't be revoked. Use /demote first.")
+		return
+	}
+
+	revoked, err := b.db.RevokeVolunteer(ctx, volunteerID, userID)
+	if err != nil {
+		slog.Error("Error revoking volunteer", "user_id", volunteerID, "err", err)
+		b.sendMessage(msg.Chat.ID, "Error revoking volunteer.")
+		return
+	}
+
+	name := b.volunteerName(ctx, volunteerID)
+	released := b.releaseVolunteerClaims(ctx, volunteerID, userID)
+	if !revoked && len(released) == 0 {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("%s isn't an approved volunteer.", name))
+		return
+	}
+
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("🚫 %s can no longer claim requests.%s", name, releasedNote(released)))
+	b.sendMessage(volunteerID, "Your Centromex volunteer approval was removed, so you can't claim requests for now. "+
+		"Please contact a coordinator if you have questions.")
+	b.notifyCoordinators(fmt.Sprintf("🚫 %s was revoked by %s.%s", name, msg.From.FirstName, releasedNote(released)))
+}
+
+func (b *Bot) handleBan(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can ban volunteers.")
+		return
+	}
+
+	volunteerID, reason, err := parseUserArgs(msg.CommandArguments())
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Usage: /ban <telegram_user_id> [reason]")
+		return
+	}
+	if b.isCoordinator(volunteerID) {
+		b.sendMessage(msg.Chat.ID, "Coordinators can't be banned. Use /demote first.")
+		return
+	}
+
+	banned, err := b.db.BanVolunteer(ctx, volunteerID, reason, userID)
+	if err != nil {
+		slog.Error("Error banning volunteer", "user_id", volunteerID, "err", err)
+		b.sendMessage(msg.Chat.ID, "Error banning volunteer.")
+		return
+	}
+
+	name := b.volunteerName(ctx, volunteerID)
+	if !banned {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("%s is already banned.", name))
+		return
+	}
+
+	released := b.releaseVolunteerClaims(ctx, volunteerID, userID)
+
+	// Also remove them from the volunteer chat so they stop seeing requests.
+	// This needs the bot to be an admin there.
+	kick := tgbotapi.BanChatMemberConfig{ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: b.volunteerChat, UserID: volunteerID}}
+	if _, err := b.api.Request(kick); err != nil {
+		slog.Warn("Error removing banned volunteer from chat", "user_id", volunteerID, "err", err)
+	}
+
+	notice := fmt.Sprintf("⛔ %s was banned by %s.", name, msg.From.FirstName)
+	if reason != "" {
+		notice = fmt.Sprintf("⛔ %s was banned by %s: %s.", name, msg.From.FirstName, reason)
+	}
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("⛔ %s is banned.%s", name, releasedNote(released)))
+	b.notifyCoordinators(notice + releasedNote(released))
+}
+
+func (b *Bot) handleUnban(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can unban volunteers.")
+		return
+	}
+
+	volunteerID, _, err := parseUserArgs(msg.CommandArguments())
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Usage: /unban <telegram_user_id>")
+		return
+	}
+
+	unbanned, err := b.db.UnbanVolunteer(ctx, volunteerID, userID)
+	if err != nil {
+		slog.Error("Error unbanning volunteer", "user_id", volunteerID, "err", err)
+		b.sendMessage(msg.Chat.ID, "Error unbanning volunteer.")
+		return
+	}
+
+	name := b.volunteerName(ctx, volunteerID)
+	if !unbanned {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("%s isn't banned.", name))
+		return
+	}
+
+	// Let them rejoin the volunteer chat
+	unkick := tgbotapi.UnbanChatMemberConfig{
+		ChatMemberConfig: tgbotapi.ChatMemberConfig{ChatID: b.volunteerChat, UserID: volunteerID},
+		OnlyIfBanned:     true,
+	}
+	if _, err := b.api.Request(unkick); err != nil {
+		slog.Warn("Error unbanning volunteer from chat", "user_id", volunteerID, "err", err)
+	}
+
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("%s is no longer banned. To let them claim requests again: /approve %d", name, volunteerID))
+	b.notifyCoordinators(fmt.Sprintf("%s was unbanned by %s", name, msg.From.FirstName))
+}
+
+func (b *Bot) handleNote(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can add volunteer notes.")
+		return
+	}
+
+	// Notes are about people, so keep them out of the group
+	if msg.Chat.ID != userID {
+		b.sendMessage(msg.Chat.ID, "⚠️ Please send /note via DM.")
+		return
+	}
+
+	volunteerID, notes, err := parseUserArgs(msg.CommandArguments())
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "Usage: /note <telegram_user_id> <notes>\nExample: /note 123456789 has car, speaks Spanish\n\n"+
+			"The notes replace any saved before. Send /note <telegram_user_id> - to clear them.")
+		return
+	}
+
+	if notes == "" {
+		v, err := b.db.GetVolunteer(ctx, volunteerID)
+		if err != nil || v.Notes == "" {
+			b.sendMessage(msg.Chat.ID, fmt.Sprintf("No notes for %s.", b.volunteerName(ctx, volunteerID)))
+			return
+		}
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("📝 %s: %s", b.volunteerName(ctx, volunteerID), v.Notes))
+		return
+	}
+	if notes == "-" {
+		notes = ""
+	}
+
+	if _, err := b.db.SetVolunteerNotes(ctx, volunteerID, notes, userID); err != nil {
+		slog.Error("Error saving volunteer notes", "user_id", volunteerID, "err", err)
+		b.sendMessage(msg.Chat.ID, "Error saving notes.")
+		return
+	}
+
+	if notes == "" {
+		b.sendMessage(msg.Chat.ID, fmt.Sprintf("📝 Notes cleared for %s.", b.volunteerName(ctx, volunteerID)))
+		return
+	}
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("📝 Notes saved for %s.", b.volunteerName(ctx, volunteerID)))
+}
+
+func (b *Bot) handleVolunteers(ctx context.Context, msg *tgbotapi.Message, userID int64) {
+	if !b.isCoordinator(userID) {
+		b.sendMessage(msg.Chat.ID, "Only coordinators can list volunteers.")
+		return
+	}
+
+	if msg.Chat.ID != userID {
+		b.sendMessage(msg.Chat.ID, "⚠️ Please send /volunteers via DM.")
+		return
+	}
+
+	volunteers, err := b.db.GetVolunteers(ctx)
+	if err != nil {
+		slog.Error("Error fetching volunteers", "err", err)
+		b.sendMessage(msg.Chat.ID, "Error fetching volunteers.")
+		return
+	}
+
+	var pending, approved, banned []models.Volunteer
+	for _, v := range volunteers {
+		switch {
+		case v.IsBanned:
+			banned = append(banned, v)
+		case v.IsApproved:
+			approved = append(approved, v)
+		case !v.IsCoordinator && !b.isCoordinator(v.TelegramID):
+			pending = append(pending, v)
+		}
+	}
+
+	var sb strings.Builder
+	writeSection := func(title string, list []models.Volunteer) {
+		sb.WriteString(fmt.Sprintf("\n%s (%d)\n", title, len(list)))
+		for _, v := range list {
+			sb.WriteString("• " + describeVolunteer(v) + "\n")
+			if v.Notes != "" {
+				sb.WriteString("   📝 " + v.Notes + "\n")
+			}
+		}
+	}
+
+	sb.WriteString("👥 VOLUNTEERS\n")
+	writeSection("⏳ NOT APPROVED", pending)
+	writeSection("✅ APPROVED", approved)
+	if len(banned) > 0 {
+		writeSection("⛔ BANNED", banned)
+	}
+	if len(pending) > 0 {
+		sb.WriteString("\nTo approve: /approve <id>")
+	}
+
+	b.sendMessage(msg.Chat.ID, sb.String())
+}
+
+// releaseVolunteerClaims takes back every request a volunteer has claimed
+// and reposts them, returning the IDs released
+func (b *Bot) releaseVolunteerClaims(ctx context.Context, volunteerID int64, actorID int64) []int64 {
+	requests, err := b.db.GetVolunteerRequests(ctx, volunteerID)
+	if err != nil {
+		slog.Error("Error fetching volunteer requests", "user_id", volunteerID, "err", err)
+		return nil
+	}
+
+	var released []int64
+	for _, req := range requests {
+		unlock := b.requestLocks.lock(req.ID)
+		err := b.db.ReleaseClaim(ctx, req.ID, actorID, true)
+		if err == nil {
+			b.repostRequest(ctx, req.ID)
+		}
+		unlock()
+
+		if err != nil {
+			slog.Error("Error releasing claim", "request_id", req.ID, "user_id", volunteerID, "err", err)
+			continue
+		}
+		released = append(released, req.ID)
+	}
+
+	if len(released) > 0 {
+		b.sendMessage(volunteerID, fmt.Sprintf("↩️ A coordinator released your claims on %s. No need to shop for them.", requestList(released)))
+	}
+	return released
+}
+
+// releasedNote tells coordinators which requests were re-posted, or is
+// empty if none were
+func releasedNote(released []int64) string {
+	if len(released) == 0 {
+		return ""
+	}
+	return " Re-posted " + requestList(released) + "."
+}
+
+func requestList(ids []int64) string {
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(list, ", ")
+}
+
+// describeVolunteer is a volunteer's name, username and ID
+func describeVolunteer(v models.Volunteer) string {
+	switch {
+	case v.DisplayName == "":
+		return strconv.FormatInt(v.TelegramID, 10)
+	case v.Username != "":
+		return fmt.Sprintf("%s (@%s, %d)", v.DisplayName, v.Username, v.TelegramID)
+	default:
+		return fmt.Sprintf("%s (%d)", v.DisplayName, v.TelegramID)
+	}
+}
@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxWebhookBody caps the size of an update we'll read. Real updates are a
+// few kilobytes at most.
+const maxWebhookBody = 1 << 20
+
+// secretTokenHeader carries the secret_token we registered with setWebhook
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Telegram only accepts 1-256 of these characters as a secret_token
+var validSecretToken = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// TelegramIPRanges are the networks Telegram sends webhook requests from,
+// as published at https://core.telegram.org/bots/webhooks
+var TelegramIPRanges = []netip.Prefix{
+	netip.MustParsePrefix("149.154.160.0/20"),
+	netip.MustParsePrefix("91.108.4.0/22"),
+}
+
+// runWebhook serves Telegram webhook updates until ctx is cancelled, then
+// stops accepting new ones
+func (b *Bot) runWebhook(ctx context.Context) error {
+	if err := b.setWebhook(); err != nil {
+		return err
+	}
+
+	slog.Info("Webhook set", "url", b.webhookURL+"/webhook", "ip_allowlist", len(b.webhookAllowedIPs) > 0)
+
+	// Set up HTTP handlers
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", b.webhookHandler())
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	server := &http.Server{
+		Addr:              ":8080",
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting webhook server", "addr", server.Addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down webhook server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// setWebhook registers our URL and secret token with Telegram. Our version
+// of telegram-bot-api has no SecretToken field, so this calls setWebhook
+// directly.
+func (b *Bot) setWebhook() error {
+	_, err := b.api.MakeRequest("setWebhook", tgbotapi.Params{
+		"url":          b.webhookURL + "/webhook",
+		"secret_token": b.webhookSecret,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	return nil
+}
+
+// webhookHandler queues incoming Telegram updates. Anything without our
+// secret token is rejected, since a forged update could claim requests as
+// any user.
+func (b *Bot) webhookHandler() http.HandlerFunc {
+	secret := []byte(b.webhookSecret)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if len(b.webhookAllowedIPs) > 0 {
+			ip, ok := b.clientIP(r)
+			if !ok || !allowedIP(ip, b.webhookAllowedIPs) {
+				slog.Warn("Rejected webhook request from outside the allowed IPs", "remote_addr", r.RemoteAddr, "client_ip", ip)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		token := []byte(r.Header.Get(secretTokenHeader))
+		if subtle.ConstantTimeCompare(token, secret) != 1 {
+			slog.Warn("Rejected webhook request without a valid secret token", "remote_addr", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			slog.Warn("Error reading webhook body", "err", err)
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.Unmarshal(body, &update); err != nil {
+			slog.Warn("Error parsing webhook update", "err", err)
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+
+		// Only acknowledge updates that are safely saved; Telegram resends
+		// the rest. Processing happens on the workers, so slow LLM
+		// translations don't hold up the response.
+		if err := b.enqueue(r.Context(), update); err != nil {
+			slog.Error("Error queueing webhook update", "update_id", update.UpdateID, "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// clientIP is the address a webhook request came from: the connection's
+// peer, or behind a reverse proxy the last address the proxy recorded in
+// webhookClientIPHeader (earlier ones can be forged by the client).
+func (b *Bot) clientIP(r *http.Request) (netip.Addr, bool) {
+	raw := ""
+	if b.webhookClientIPHeader != "" {
+		values := strings.Split(r.Header.Get(b.webhookClientIPHeader), ",")
+		raw = strings.TrimSpace(values[len(values)-1])
+	} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		raw = host
+	}
+
+	ip, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
+
+func allowedIP(ip netip.Addr, ranges []netip.Prefix) bool {
+	for _, prefix := range ranges {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
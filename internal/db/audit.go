@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// recordAudit saves an audit event as part of the change it describes
+func recordAudit(ctx context.Context, tx *sql.Tx, event models.AuditEvent) error {
+	var details sql.NullString
+	if event.Details != "" {
+		details = sql.NullString{String: event.Details, Valid: true}
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_events (actor_id, action, target_id, details, created_at) VALUES (?, ?, ?, ?, ?)`,
+		event.ActorID, event.Action, event.TargetID, details, time.Now(),
+	)
+	return err
+}
+
+// GetAuditEvents returns the changes made to a user, newest first
+func (db *DB) GetAuditEvents(ctx context.Context, targetID int64, limit int) ([]models.AuditEvent, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, actor_id, action, target_id, COALESCE(details, ''), created_at
+		FROM audit_events WHERE target_id = ? ORDER BY id DESC LIMIT ?
+	`, targetID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.AuditEvent
+	for rows.Next() {
+		var e models.AuditEvent
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetID, &e.Details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetLatestAuditEvent returns the most recent action of the given kind
+// taken on a user, or nil if there is none
+func (db *DB) GetLatestAuditEvent(ctx context.Context, targetID int64, action models.AuditAction) (*models.AuditEvent, error) {
+	var e models.AuditEvent
+	err := db.conn.QueryRowContext(ctx, `
+		SELECT id, actor_id, action, target_id, COALESCE(details, ''), created_at
+		FROM audit_events WHERE target_id = ? AND action = ? ORDER BY id DESC LIMIT 1
+	`, targetID, action).Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetID, &e.Details, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+func TestGetLatestAuditEvent(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	if event, err := db.GetLatestAuditEvent(ctx, 100, models.AuditPromote); err != nil || event != nil {
+		t.Fatalf("before any promotion got %+v, %v; want nil", event, err)
+	}
+
+	// Later changes of other kinds don't hide the promotion
+	if _, err := db.SetCoordinator(ctx, 100, true, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SetVolunteerNotes(ctx, 100, "Has a car", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ApproveVolunteer(ctx, 100, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := db.GetLatestAuditEvent(ctx, 100, models.AuditPromote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event == nil || event.ActorID != 1 || event.Action != models.AuditPromote || event.TargetID != 100 {
+		t.Fatalf("got %+v, want the promotion by 1", event)
+	}
+
+	// Only the latest one is returned, and only for that user
+	if _, err := db.SetCoordinator(ctx, 100, false, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SetCoordinator(ctx, 100, true, 5); err != nil {
+		t.Fatal(err)
+	}
+	if event, err := db.GetLatestAuditEvent(ctx, 100, models.AuditPromote); err != nil || event.ActorID != 5 {
+		t.Errorf("got %+v, %v; want the promotion by 5", event, err)
+	}
+	if event, err := db.GetLatestAuditEvent(ctx, 200, models.AuditPromote); err != nil || event != nil {
+		t.Errorf("another user got %+v, %v; want nil", event, err)
+	}
+}
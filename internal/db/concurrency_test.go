@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+const workers = 20
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(t.TempDir()+"/test.db", "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// postedRequest creates a request ready to be claimed
+func postedRequest(t *testing.T, db *DB, items ...models.RequestItem) int64 {
+	t.Helper()
+	ctx := context.Background()
+	req, err := db.CreateRequest(ctx, "2 libras de arroz", "$40", "West Side", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateRequestTranslation(ctx, req.ID, "2 lbs rice", items, "", models.StatusPosted, false); err != nil {
+		t.Fatal(err)
+	}
+	return req.ID
+}
+
+// hammer runs fn from workers goroutines at once and returns their errors
+func hammer(fn func(i int) error) []error {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func succeeded(errs []error) []int {
+	var ok []int
+	for i, err := range errs {
+		if err == nil {
+			ok = append(ok, i)
+		}
+	}
+	return ok
+}
+
+func TestClaimRequestConcurrent(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	id := postedRequest(t, db)
+
+	errs := hammer(func(i int) error {
+		return db.ClaimRequest(ctx, id, int64(100+i), fmt.Sprintf("Volunteer %d", i))
+	})
+
+	winners := succeeded(errs)
+	if len(winners) != 1 {
+		t.Fatalf("%d claims succeeded, want 1: %v", len(winners), errs)
+	}
+
+	req, err := db.GetRequest(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(100 + winners[0]); req.Status != models.StatusClaimed || req.ClaimedBy != want {
+		t.Errorf("request is %s by %d, want claimed by %d", req.Status, req.ClaimedBy, want)
+	}
+}
+
+func TestCompleteRequestConcurrent(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	id := postedRequest(t, db)
+	if err := db.ClaimRequest(ctx, id, 100, "Volunteer"); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := hammer(func(int) error {
+		return db.CompleteRequest(ctx, id, 100)
+	})
+
+	if n := len(succeeded(errs)); n != 1 {
+		t.Fatalf("%d completions succeeded, want 1: %v", n, errs)
+	}
+}
+
+// An address saved while the request is being delivered must not outlive
+// the delivery
+func TestSaveContactRacingComplete(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	for round := range 10 {
+		id := postedRequest(t, db)
+		if err := db.ClaimRequest(ctx, id, 100, "Volunteer"); err != nil {
+			t.Fatal(err)
+		}
+
+		errs := hammer(func(i int) error {
+			if i == 0 {
+				return db.CompleteRequest(ctx, id, 100)
+			}
+			return db.SaveContact(ctx, id, fmt.Sprintf("%d Main St", i), "")
+		})
+		if errs[0] != nil {
+			t.Fatalf("round %d: complete failed: %v", round, errs[0])
+		}
+
+		if contact, err := db.GetContact(ctx, id); err == nil {
+			t.Fatalf("round %d: address %q survived delivery", round, contact.Address)
+		}
+	}
+}
+
+// Concurrent saves of different contact fields are merged, not lost
+func TestSaveContactConcurrent(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	id := postedRequest(t, db)
+
+	errs := hammer(func(i int) error {
+		if i%2 == 0 {
+			return db.SaveContact(ctx, id, "1234 W Maryland Ave", "")
+		}
+		return db.SaveContact(ctx, id, "", "651-555-1234")
+	})
+	if n := len(succeeded(errs)); n != workers {
+		t.Fatalf("%d saves succeeded, want %d: %v", n, workers, errs)
+	}
+
+	contact, err := db.GetContact(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contact.Address != "1234 W Maryland Ave" || contact.Phone != "651-555-1234" {
+		t.Errorf("contact = %q, %q; want both fields saved", contact.Address, contact.Phone)
+	}
+}
+
+// Every successful tap on a checklist item advances it exactly once
+func TestCycleItemStatusConcurrent(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	id := postedRequest(t, db, models.RequestItem{Name: "Rice", Category: "PANTRY"})
+	if err := db.ClaimRequest(ctx, id, 100, "Volunteer"); err != nil {
+		t.Fatal(err)
+	}
+	req, err := db.GetRequest(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemID := req.Items[0].ID
+
+	errs := hammer(func(int) error {
+		_, err := db.CycleItemStatus(ctx, itemID, 100)
+		return err
+	})
+
+	want := models.ItemPending
+	for range succeeded(errs) {
+		want = want.Next()
+	}
+	req, err = db.GetRequest(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Items[0].Status; got != want {
+		t.Errorf("item is %s after %d taps, want %s", got, len(succeeded(errs)), want)
+	}
+}
+
+// A release racing a claim by someone else leaves exactly one owner
+func TestReleaseRacingClaim(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	for round := range 10 {
+		id := postedRequest(t, db)
+		if err := db.ClaimRequest(ctx, id, 100, "First"); err != nil {
+			t.Fatal(err)
+		}
+
+		errs := hammer(func(i int) error {
+			if i == 0 {
+				return db.ReleaseClaim(ctx, id, 100, false)
+			}
+			return db.ClaimRequest(ctx, id, int64(200+i), "Second")
+		})
+		if errs[0] != nil {
+			t.Fatalf("round %d: release failed: %v", round, errs[0])
+		}
+
+		claims := succeeded(errs[1:])
+		req, err := db.GetRequest(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch len(claims) {
+		case 0:
+			if req.Status != models.StatusPosted || req.ClaimedBy != 0 {
+				t.Errorf("round %d: request is %s by %d, want posted", round, req.Status, req.ClaimedBy)
+			}
+		case 1:
+			if want := int64(200 + claims[0] + 1); req.Status != models.StatusClaimed || req.ClaimedBy != want {
+				t.Errorf("round %d: request is %s by %d, want claimed by %d", round, req.Status, req.ClaimedBy, want)
+			}
+		default:
+			t.Errorf("round %d: %d claims succeeded, want at most 1", round, len(claims))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// encPrefix marks a column value as sealed by a crypter. Values without it
+// are legacy plaintext written before column encryption existed.
+const encPrefix = "enc:v1:"
+
+// keyCheckPlaintext is sealed into the meta table so startup can tell
+// whether DB_ENCRYPTION_KEY matches the key the data was written with.
+const keyCheckPlaintext = "centromex-key-check"
+
+// ErrWrongKey is returned when the configured key cannot decrypt the data
+var ErrWrongKey = errors.New("encryption key does not match this database")
+
+// crypter seals sensitive column values with AES-256-GCM
+type crypter struct {
+	aead cipher.AEAD
+}
+
+// newCrypter derives an AES-256 key from the passphrase and builds a crypter
+func newCrypter(passphrase string) (*crypter, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("encryption key is empty")
+	}
+
+	block, err := aes.NewCipher(deriveKey(passphrase))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &crypter{aead: aead}, nil
+}
+
+// deriveKey turns an operator-supplied passphrase into a 32-byte key
+func deriveKey(passphrase string) []byte {
+	mac := hmac.New(sha256.New, []byte("centromex-grocery-bot/db-encryption"))
+	mac.Write([]byte(passphrase))
+	return mac.Sum(nil)
+}
+
+// encrypt seals plaintext as encPrefix + base64(nonce || ciphertext)
+func (c *crypter) encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value produced by encrypt
+func (c *crypter) decrypt(value string) (string, error) {
+	if !isEncrypted(value) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encPrefix))
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return "", fmt.Errorf("malformed ciphertext: too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", ErrWrongKey
+	}
+
+	return string(plaintext), nil
+}
+
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix)
+}
@@ -1,8 +1,11 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/centromex/grocery-bot/internal/models"
@@ -10,72 +13,196 @@ import (
 )
 
 type DB struct {
-	conn *sql.DB
+	conn       *sql.DB
+	crypt      *crypter
+	keyVersion int // Version of crypt's key, recorded on every encrypted row
 }
 
-// New creates a new encrypted SQLite database connection
+// execer is the subset of *sql.DB and *sql.Tx used by schema helpers
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// querier is the subset of *sql.DB and *sql.Tx used by reads shared
+// between transactions and plain queries
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// New opens the SQLite database. Sensitive columns (original request text
+// and addresses) are encrypted in the application with a key derived from
+// encryptionKey; New fails if that key cannot decrypt existing data.
 func New(dbPath string, encryptionKey string) (*DB, error) {
-	// SQLCipher connection string with encryption key
-	connStr := fmt.Sprintf("%s?_pragma_key=%s&_pragma_cipher_page_size=4096", dbPath, encryptionKey)
+	crypt, err := newCrypter(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
 
-	conn, err := sql.Open("sqlite3", connStr)
+	conn, err := sql.Open("sqlite3", dataSource(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	db := &DB{conn: conn}
+	db := &DB{conn: conn, crypt: crypt}
 	if err := db.migrate(); err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if err := db.verifyKey(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := db.encryptLegacyRows(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to encrypt existing rows: %w", err)
+	}
+
 	return db, nil
 }
 
-func (db *DB) migrate() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS requests (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		original_text TEXT NOT NULL,
-		translated_text TEXT,
-		budget TEXT,
-		zone TEXT,
-		status TEXT NOT NULL DEFAULT 'new',
-		claimed_by INTEGER,
-		claimed_by_name TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		delivered_at DATETIME
-	);
-
-	CREATE TABLE IF NOT EXISTS volunteers (
-		telegram_id INTEGER PRIMARY KEY,
-		username TEXT,
-		display_name TEXT,
-		is_approved INTEGER DEFAULT 0,
-		is_coordinator INTEGER DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS addresses (
-		request_id INTEGER PRIMARY KEY,
-		address TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (request_id) REFERENCES requests(id)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_requests_status ON requests(status);
-	CREATE INDEX IF NOT EXISTS idx_requests_claimed_by ON requests(claimed_by);
-	`
-
-	_, err := db.conn.Exec(schema)
-	return err
+// dataSource adds the connection options every caller needs to dbPath.
+// Updates are processed concurrently, so a write waits up to five seconds
+// for another to finish instead of failing with "database is locked", and
+// transactions take the write lock up front so two that read a request
+// before updating it can't deadlock.
+func dataSource(dbPath string) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return dbPath + sep + "_busy_timeout=5000&_txlock=immediate"
 }
 
-// CreateRequest creates a new grocery request
-func (db *DB) CreateRequest(originalText, budget, zone string) (*models.Request, error) {
-	result, err := db.conn.Exec(
-		`INSERT INTO requests (original_text, budget, zone, status) VALUES (?, ?, ?, ?)`,
-		originalText, budget, zone, models.StatusNew,
+// verifyKey checks the configured key against the sealed check value in
+// meta (creating it on first run) and against a sample of encrypted rows.
+func (db *DB) verifyKey() error {
+	if _, pending, err := getMeta(db.conn, "rekey_version"); err != nil {
+		return err
+	} else if pending {
+		return ErrRekeyInProgress
+	}
+
+	check, ok, err := getMeta(db.conn, "key_check")
+	if err != nil {
+		return fmt.Errorf("failed to read key check: %w", err)
+	}
+	if !ok {
+		sealed, err := db.crypt.encrypt(keyCheckPlaintext)
+		if err != nil {
+			return err
+		}
+		if err := setMeta(db.conn, "key_check", sealed); err != nil {
+			return err
+		}
+		db.keyVersion = 1
+		return setMeta(db.conn, "key_version", "1")
+	}
+
+	if plain, err := db.crypt.decrypt(check); err != nil || plain != keyCheckPlaintext {
+		return fmt.Errorf("DB_ENCRYPTION_KEY cannot decrypt this database: %w", ErrWrongKey)
+	}
+
+	if db.keyVersion, err = currentKeyVersion(db.conn); err != nil {
+		return err
+	}
+
+	samples := []string{
+		`SELECT original_text FROM requests WHERE original_text LIKE 'enc:%' LIMIT 1`,
+		`SELECT address FROM addresses WHERE address LIKE 'enc:%' LIMIT 1`,
+	}
+	for _, query := range samples {
+		var value string
+		err := db.conn.QueryRow(query).Scan(&value)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := db.crypt.decrypt(value); err != nil {
+			return fmt.Errorf("DB_ENCRYPTION_KEY cannot decrypt existing rows: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// encryptLegacyRows encrypts plaintext values left over from before
+// column encryption was introduced.
+func (db *DB) encryptLegacyRows() error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range sensitiveTables {
+		for _, column := range t.columns {
+			if err := encryptColumn(tx, db.crypt, db.keyVersion, t, column); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func encryptColumn(tx *sql.Tx, crypt *crypter, keyVersion int, t sensitiveTable, column string) error {
+	rows, err := tx.Query(fmt.Sprintf(
+		`SELECT %s, %s FROM %s WHERE %s NOT LIKE 'enc:%%'`, t.idColumn, column, t.table, column,
+	))
+	if err != nil {
+		return err
+	}
+
+	plain := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		plain[id] = value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, value := range plain {
+		sealed, err := crypt.encrypt(value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			fmt.Sprintf(`UPDATE %s SET %s = ?, key_version = ? WHERE %s = ?`, t.table, column, t.idColumn),
+			sealed, keyVersion, id,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateRequest creates a new grocery request. createdBy is the
+// coordinator entering it, who stays the family's point of contact.
+func (db *DB) CreateRequest(ctx context.Context, originalText, budget, zone string, createdBy int64) (*models.Request, error) {
+	sealed, err := db.crypt.encrypt(originalText)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := db.conn.ExecContext(ctx,
+		`INSERT INTO requests (original_text, budget, zone, status, created_by, key_version) VALUES (?, ?, ?, ?, ?, ?)`,
+		sealed, budget, zone, models.StatusNew, createdBy, db.keyVersion,
 	)
 	if err != nil {
 		return nil, err
@@ -92,27 +219,51 @@ func (db *DB) CreateRequest(originalText, budget, zone string) (*models.Request,
 		Budget:       budget,
 		Zone:         zone,
 		Status:       models.StatusNew,
+		CreatedBy:    createdBy,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}, nil
 }
 
-// UpdateRequestTranslation updates the translated text and marks as posted
-func (db *DB) UpdateRequestTranslation(id int64, translatedText string) error {
-	_, err := db.conn.Exec(
-		`UPDATE requests SET translated_text = ?, status = ?, updated_at = ? WHERE id = ?`,
-		translatedText, models.StatusPosted, time.Now(), id,
-	)
-	return err
+// requestState is the part of a request that status transitions check
+type requestState struct {
+	status    models.RequestStatus
+	claimedBy int64
 }
 
-// ClaimRequest marks a request as claimed by a volunteer
-func (db *DB) ClaimRequest(requestID int64, volunteerID int64, volunteerName string) error {
-	result, err := db.conn.Exec(
-		`UPDATE requests SET status = ?, claimed_by = ?, claimed_by_name = ?, updated_at = ?
-		 WHERE id = ? AND status = ?`,
-		models.StatusClaimed, volunteerID, volunteerName, time.Now(), requestID, models.StatusPosted,
-	)
+func loadRequestState(ctx context.Context, tx *sql.Tx, requestID int64) (*requestState, error) {
+	var st requestState
+	var claimedBy sql.NullInt64
+	err := tx.QueryRowContext(ctx, `SELECT status, claimed_by FROM requests WHERE id = ?`, requestID).Scan(&st.status, &claimedBy)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("request not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	st.claimedBy = claimedBy.Int64
+	return &st, nil
+}
+
+// setStatus moves a request from one status to another, enforcing the
+// lifecycle in models. The update only applies if the request still has
+// from's status and claimant, so a concurrent change makes it fail instead
+// of being overwritten. set and args add extra column assignments.
+func setStatus(ctx context.Context, tx *sql.Tx, requestID int64, from *requestState, to models.RequestStatus, set string, args ...any) error {
+	if !from.status.CanTransitionTo(to) {
+		return &models.TransitionError{From: from.status, To: to}
+	}
+
+	query := `UPDATE requests SET status = ?, updated_at = ?`
+	if set != "" {
+		query += ", " + set
+	}
+	query += ` WHERE id = ? AND status = ? AND COALESCE(claimed_by, 0) = ?`
+
+	params := append([]any{to, time.Now()}, args...)
+	params = append(params, requestID, from.status, from.claimedBy)
+
+	result, err := tx.ExecContext(ctx, query, params...)
 	if err != nil {
 		return err
 	}
@@ -122,42 +273,387 @@ func (db *DB) ClaimRequest(requestID int64, volunteerID int64, volunteerName str
 		return err
 	}
 	if rows == 0 {
-		return fmt.Errorf("request not available for claiming")
+		return fmt.Errorf("request changed while updating, please try again")
 	}
 
 	return nil
 }
 
-// CompleteRequest marks a request as delivered and deletes the address
-func (db *DB) CompleteRequest(requestID int64, volunteerID int64) error {
-	tx, err := db.conn.Begin()
+// UpdateRequestTranslation saves the translation of a new request and moves
+// it to next: posted, or pending_review if a coordinator must approve it first.
+// needsTranslation marks a request every translator failed on; it has no
+// translated text, and is shown from its encrypted original_text instead.
+func (db *DB) UpdateRequestTranslation(ctx context.Context, id int64, translatedText string, items []models.RequestItem, notes string, next models.RequestStatus, needsTranslation bool) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	st, err := loadRequestState(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if st.status != models.StatusNew {
+		return fmt.Errorf("request is already %s", st.status)
+	}
+
+	err = setStatus(ctx, tx, id, st, next, `translated_text = ?, notes = ?, needs_translation = ?`,
+		translatedText, notes, needsTranslation)
+	if err != nil {
+		return err
+	}
+
+	if err := saveRequestItems(ctx, tx, id, items); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RetranslateRequest replaces the untranslated Spanish of a request that was
+// posted or held for review with a translation that has since succeeded.
+// Claimed requests are left alone so a volunteer's list doesn't change
+// under them.
+func (db *DB) RetranslateRequest(ctx context.Context, id int64, translatedText string, items []models.RequestItem, notes string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE requests SET translated_text = ?, notes = ?, needs_translation = 0, updated_at = ?
+		WHERE id = ? AND needs_translation = 1 AND status IN (?, ?)
+	`, translatedText, notes, time.Now(), id, models.StatusPosted, models.StatusPendingReview)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("request changed while updating, please try again")
+	}
+
+	if err := saveRequestItems(ctx, tx, id, items); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetUntranslatedRequests returns the IDs of requests translation should be
+// retried for: those stuck in new for longer than stuckFor (e.g. the bot
+// stopped mid-translation) and open ones still showing the family's Spanish
+func (db *DB) GetUntranslatedRequests(ctx context.Context, stuckFor time.Duration) ([]int64, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id FROM requests
+		WHERE (status = ? AND created_at < ?) OR (needs_translation = 1 AND status IN (?, ?))
+		ORDER BY id
+	`, models.StatusNew, time.Now().Add(-stuckFor), models.StatusPosted, models.StatusPendingReview)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// saveRequestItems replaces a request's shopping list
+func saveRequestItems(ctx context.Context, tx *sql.Tx, requestID int64, items []models.RequestItem) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM request_items WHERE request_id = ?`, requestID); err != nil {
+		return err
+	}
+
+	for i, item := range items {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO request_items (request_id, position, name, original, quantity, unit, category, notes, uncertain)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, requestID, i, item.Name, item.Original, item.Quantity, item.Unit, item.Category, item.Notes, item.Uncertain)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CycleItemStatus moves an item to the next checklist status and returns
+// it. Only the volunteer who claimed the request can check items off, and
+// only while it is claimed or being shopped.
+func (db *DB) CycleItemStatus(ctx context.Context, itemID int64, volunteerID int64) (*models.RequestItem, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var requestID int64
+	var status models.ItemStatus
+	err = tx.QueryRowContext(ctx, `SELECT request_id, status FROM request_items WHERE id = ?`, itemID).Scan(&requestID, &status)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("item not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := loadRequestState(ctx, tx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if st.claimedBy != volunteerID {
+		return nil, fmt.Errorf("you don't have request #%d claimed", requestID)
+	}
+	if st.status != models.StatusClaimed && st.status != models.StatusShopping {
+		return nil, fmt.Errorf("request #%d is %s", requestID, st.status)
+	}
+
+	// Checked against the request too, in case it was released meanwhile
+	result, err := tx.ExecContext(ctx, `
+		UPDATE request_items SET status = ?
+		WHERE id = ? AND status = ? AND EXISTS (
+			SELECT 1 FROM requests WHERE id = ? AND status = ? AND claimed_by = ?
+		)
+	`, status.Next(), itemID, status, requestID, st.status, volunteerID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, fmt.Errorf("request changed while updating, please try again")
+	}
+
+	items, err := getRequestItems(ctx, tx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if items[i].ID == itemID {
+			return &items[i], nil
+		}
+	}
+	return nil, fmt.Errorf("item not found")
+}
+
+// getRequestItems returns a request's shopping list in order
+func getRequestItems(ctx context.Context, conn querier, requestID int64) ([]models.RequestItem, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT id, request_id, position, name, COALESCE(original, ''), COALESCE(quantity, ''),
+		       COALESCE(unit, ''), category, COALESCE(notes, ''), uncertain, status
+		FROM request_items WHERE request_id = ? ORDER BY position
+	`, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.RequestItem
+	for rows.Next() {
+		var item models.RequestItem
+		err := rows.Scan(
+			&item.ID, &item.RequestID, &item.Position, &item.Name, &item.Original, &item.Quantity,
+			&item.Unit, &item.Category, &item.Notes, &item.Uncertain, &item.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ApproveRequest posts a request that was waiting for review
+func (db *DB) ApproveRequest(ctx context.Context, id int64) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	st, err := loadRequestState(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if st.status != models.StatusPendingReview {
+		return fmt.Errorf("request is %s, not waiting for review", st.status)
+	}
+
+	if err := setStatus(ctx, tx, id, st, models.StatusPosted, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceTranslation replaces the translation of a request under review
+// with a coordinator's corrected text. The structured items are dropped,
+// since they'd no longer match what the coordinator wrote.
+func (db *DB) ReplaceTranslation(ctx context.Context, id int64, translatedText string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE requests SET translated_text = ?, notes = NULL, needs_translation = 0, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, translatedText, time.Now(), id, models.StatusPendingReview)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("request is not waiting for review")
+	}
+
+	if err := saveRequestItems(ctx, tx, id, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateRequestDeliveryWindow sets when the family can receive groceries
+func (db *DB) UpdateRequestDeliveryWindow(ctx context.Context, id int64, window string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE requests SET delivery_window = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, window, id)
+	return err
+}
+
+// UpdateRequestBudget sets a request's budget, e.g. once the translator
+// has picked it out of the family's message
+func (db *DB) UpdateRequestBudget(ctx context.Context, id int64, budget string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		UPDATE requests SET budget = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, budget, id)
+	return err
+}
+
+// ClaimRequest marks a request as claimed by a volunteer
+func (db *DB) ClaimRequest(ctx context.Context, requestID int64, volunteerID int64, volunteerName string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	st, err := loadRequestState(ctx, tx, requestID)
+	if err != nil {
+		return err
+	}
+	if st.status != models.StatusPosted {
+		return fmt.Errorf("request not available for claiming")
+	}
+
+	err = setStatus(ctx, tx, requestID, st, models.StatusClaimed,
+		`claimed_by = ?, claimed_by_name = ?`, volunteerID, volunteerName)
+	if err != nil {
+		return fmt.Errorf("request not available for claiming")
+	}
+
+	return tx.Commit()
+}
+
+// StartShopping marks a claimed request as being shopped for
+func (db *DB) StartShopping(ctx context.Context, requestID int64, volunteerID int64) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	st, err := loadRequestState(ctx, tx, requestID)
+	if err != nil {
+		return err
+	}
+	if st.claimedBy != volunteerID {
+		return fmt.Errorf("you don't have this request claimed")
+	}
+
+	if err := setStatus(ctx, tx, requestID, st, models.StatusShopping, ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CompleteRequest marks a request as delivered and deletes the contact details
+func (db *DB) CompleteRequest(ctx context.Context, requestID int64, volunteerID int64) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	// Verify the volunteer owns this claim
-	var claimedBy int64
-	err = tx.QueryRow(`SELECT claimed_by FROM requests WHERE id = ?`, requestID).Scan(&claimedBy)
+	st, err := loadRequestState(ctx, tx, requestID)
 	if err != nil {
 		return err
 	}
-	if claimedBy != volunteerID {
+	if st.claimedBy != volunteerID {
 		return fmt.Errorf("you don't have this request claimed")
 	}
 
 	// Mark as delivered
-	now := time.Now()
-	_, err = tx.Exec(
-		`UPDATE requests SET status = ?, delivered_at = ?, updated_at = ? WHERE id = ?`,
-		models.StatusDelivered, now, now, requestID,
-	)
+	err = setStatus(ctx, tx, requestID, st, models.StatusDelivered, `delivered_at = ?`, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Delete the address and phone immediately
+	_, err = tx.ExecContext(ctx, `DELETE FROM addresses WHERE request_id = ?`, requestID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReleaseClaim returns a claimed request to the open pool. Only the
+// volunteer holding the claim may release it unless force is set, which
+// coordinators use to take a claim back.
+func (db *DB) ReleaseClaim(ctx context.Context, requestID int64, actorID int64, force bool) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	st, err := loadRequestState(ctx, tx, requestID)
 	if err != nil {
 		return err
 	}
 
-	// Delete the address immediately
-	_, err = tx.Exec(`DELETE FROM addresses WHERE request_id = ?`, requestID)
+	if st.status != models.StatusClaimed && st.status != models.StatusShopping {
+		return fmt.Errorf("request is not claimed")
+	}
+	if !force && st.claimedBy != actorID {
+		return fmt.Errorf("you don't have this request claimed")
+	}
+
+	err = setStatus(ctx, tx, requestID, st, models.StatusPosted, `claimed_by = NULL, claimed_by_name = NULL`)
+	if err != nil {
+		return err
+	}
+
+	// The next volunteer starts with a fresh checklist
+	_, err = tx.ExecContext(ctx, `UPDATE request_items SET status = ? WHERE request_id = ?`, models.ItemPending, requestID)
 	if err != nil {
 		return err
 	}
@@ -165,20 +661,57 @@ func (db *DB) CompleteRequest(requestID int64, volunteerID int64) error {
 	return tx.Commit()
 }
 
+// CancelRequest cancels a request that hasn't been delivered and deletes
+// its contact details, since they will never be needed.
+func (db *DB) CancelRequest(ctx context.Context, requestID int64) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	st, err := loadRequestState(ctx, tx, requestID)
+	if err != nil {
+		return err
+	}
+
+	if err := setStatus(ctx, tx, requestID, st, models.StatusCancelled, ""); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM addresses WHERE request_id = ?`, requestID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetCardMessageID records the volunteer-chat message that shows a request
+func (db *DB) SetCardMessageID(ctx context.Context, requestID int64, messageID int) error {
+	_, err := db.conn.ExecContext(ctx,
+		`UPDATE requests SET card_message_id = ? WHERE id = ?`, messageID, requestID,
+	)
+	return err
+}
+
 // GetRequest retrieves a request by ID
-func (db *DB) GetRequest(id int64) (*models.Request, error) {
+func (db *DB) GetRequest(ctx context.Context, id int64) (*models.Request, error) {
 	var req models.Request
 	var deliveredAt sql.NullTime
 	var claimedBy sql.NullInt64
 	var claimedByName sql.NullString
+	var cardMessageID sql.NullInt64
+	var createdBy sql.NullInt64
 
-	err := db.conn.QueryRow(
-		`SELECT id, original_text, translated_text, budget, zone, status,
-		        claimed_by, claimed_by_name, created_at, updated_at, delivered_at
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT id, original_text, COALESCE(translated_text, ''), COALESCE(notes, ''), budget, zone,
+		        COALESCE(delivery_window, ''), needs_translation, status,
+		        claimed_by, claimed_by_name, card_message_id, created_by, created_at, updated_at, delivered_at
 		 FROM requests WHERE id = ?`, id,
 	).Scan(
-		&req.ID, &req.OriginalText, &req.TranslatedText, &req.Budget, &req.Zone,
-		&req.Status, &claimedBy, &claimedByName, &req.CreatedAt, &req.UpdatedAt, &deliveredAt,
+		&req.ID, &req.OriginalText, &req.TranslatedText, &req.Notes, &req.Budget, &req.Zone,
+		&req.DeliveryWindow, &req.NeedsTranslation, &req.Status, &claimedBy, &claimedByName, &cardMessageID, &createdBy, &req.CreatedAt, &req.UpdatedAt, &deliveredAt,
 	)
 	if err != nil {
 		return nil, err
@@ -190,17 +723,29 @@ func (db *DB) GetRequest(id int64) (*models.Request, error) {
 	if claimedByName.Valid {
 		req.ClaimedByName = claimedByName.String
 	}
+	if cardMessageID.Valid {
+		req.CardMessageID = int(cardMessageID.Int64)
+	}
+	req.CreatedBy = createdBy.Int64
 	if deliveredAt.Valid {
 		req.DeliveredAt = &deliveredAt.Time
 	}
 
+	if req.OriginalText, err = db.crypt.decrypt(req.OriginalText); err != nil {
+		return nil, err
+	}
+
+	if req.Items, err = getRequestItems(ctx, db.conn, id); err != nil {
+		return nil, err
+	}
+
 	return &req, nil
 }
 
 // GetOpenRequests returns all requests that are posted but not claimed
-func (db *DB) GetOpenRequests() ([]models.Request, error) {
-	rows, err := db.conn.Query(
-		`SELECT id, original_text, translated_text, budget, zone, status, created_at, updated_at
+func (db *DB) GetOpenRequests(ctx context.Context) ([]models.Request, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT id, original_text, translated_text, COALESCE(notes, ''), budget, zone, needs_translation, status, created_at, updated_at
 		 FROM requests WHERE status = ? ORDER BY created_at ASC`, models.StatusPosted,
 	)
 	if err != nil {
@@ -212,22 +757,40 @@ func (db *DB) GetOpenRequests() ([]models.Request, error) {
 	for rows.Next() {
 		var req models.Request
 		err := rows.Scan(
-			&req.ID, &req.OriginalText, &req.TranslatedText, &req.Budget, &req.Zone,
-			&req.Status, &req.CreatedAt, &req.UpdatedAt,
+			&req.ID, &req.OriginalText, &req.TranslatedText, &req.Notes, &req.Budget, &req.Zone,
+			&req.NeedsTranslation, &req.Status, &req.CreatedAt, &req.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if req.OriginalText, err = db.crypt.decrypt(req.OriginalText); err != nil {
+			return nil, err
+		}
 		requests = append(requests, req)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return db.withItems(ctx, requests)
+}
 
+// withItems loads the shopping list of each request
+func (db *DB) withItems(ctx context.Context, requests []models.Request) ([]models.Request, error) {
+	for i := range requests {
+		items, err := getRequestItems(ctx, db.conn, requests[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		requests[i].Items = items
+	}
 	return requests, nil
 }
 
 // GetVolunteerRequests returns requests claimed by a specific volunteer
-func (db *DB) GetVolunteerRequests(volunteerID int64) ([]models.Request, error) {
-	rows, err := db.conn.Query(
-		`SELECT id, original_text, translated_text, budget, zone, status, created_at, updated_at
+func (db *DB) GetVolunteerRequests(ctx context.Context, volunteerID int64) ([]models.Request, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT id, original_text, translated_text, COALESCE(notes, ''), budget, zone, status, created_at, updated_at
 		 FROM requests WHERE claimed_by = ? AND status IN (?, ?) ORDER BY created_at DESC`,
 		volunteerID, models.StatusClaimed, models.StatusShopping,
 	)
@@ -240,48 +803,159 @@ func (db *DB) GetVolunteerRequests(volunteerID int64) ([]models.Request, error)
 	for rows.Next() {
 		var req models.Request
 		err := rows.Scan(
-			&req.ID, &req.OriginalText, &req.TranslatedText, &req.Budget, &req.Zone,
+			&req.ID, &req.OriginalText, &req.TranslatedText, &req.Notes, &req.Budget, &req.Zone,
 			&req.Status, &req.CreatedAt, &req.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if req.OriginalText, err = db.crypt.decrypt(req.OriginalText); err != nil {
+			return nil, err
+		}
 		requests = append(requests, req)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return requests, nil
+	return db.withItems(ctx, requests)
 }
 
-// SaveAddress stores a delivery address (encrypted with the DB)
-func (db *DB) SaveAddress(requestID int64, address string) error {
-	_, err := db.conn.Exec(
-		`INSERT OR REPLACE INTO addresses (request_id, address, created_at) VALUES (?, ?, ?)`,
-		requestID, address, time.Now(),
-	)
-	return err
+// SaveContact stores a request's delivery address and phone, encrypted.
+// An empty field leaves any previously saved value in place.
+func (db *DB) SaveContact(ctx context.Context, requestID int64, address, phone string) error {
+	return db.saveContact(ctx, models.Contact{RequestID: requestID, Address: address, Phone: phone})
 }
 
-// GetAddress retrieves the address for a request
-func (db *DB) GetAddress(requestID int64) (string, error) {
-	var address string
-	err := db.conn.QueryRow(`SELECT address FROM addresses WHERE request_id = ?`, requestID).Scan(&address)
-	return address, err
+// LinkFamilyChat records the family's own Telegram chat for a request, so
+// messages can be relayed to them directly. It is stored encrypted with
+// the rest of the contact details.
+func (db *DB) LinkFamilyChat(ctx context.Context, requestID int64, chatID int64) error {
+	return db.saveContact(ctx, models.Contact{RequestID: requestID, ChatID: chatID})
 }
 
-// AddVolunteer adds or updates a volunteer
-func (db *DB) AddVolunteer(telegramID int64, username, displayName string, isApproved bool) error {
-	_, err := db.conn.Exec(
-		`INSERT OR REPLACE INTO volunteers (telegram_id, username, display_name, is_approved, created_at)
-		 VALUES (?, ?, ?, ?, ?)`,
-		telegramID, username, displayName, isApproved, time.Now(),
-	)
+// saveContact merges update into the request's contact details; zero
+// fields keep their saved values. Contacts can't be saved for delivered or
+// cancelled requests, whose details have already been deleted.
+func (db *DB) saveContact(ctx context.Context, update models.Contact) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	st, err := loadRequestState(ctx, tx, update.RequestID)
+	if err != nil {
+		return err
+	}
+	if st.status == models.StatusDelivered || st.status == models.StatusCancelled {
+		return fmt.Errorf("request is already %s", st.status)
+	}
+
+	existing, err := db.getContact(ctx, tx, update.RequestID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if existing != nil {
+		if update.Address == "" {
+			update.Address = existing.Address
+		}
+		if update.Phone == "" {
+			update.Phone = existing.Phone
+		}
+		if update.ChatID == 0 {
+			update.ChatID = existing.ChatID
+		}
+	}
+
+	sealedAddress, err := db.crypt.encrypt(update.Address)
+	if err != nil {
+		return err
+	}
+	sealedPhone, err := db.crypt.encrypt(update.Phone)
+	if err != nil {
+		return err
+	}
+	var sealedChat sql.NullString
+	if update.ChatID != 0 {
+		sealedChat.Valid = true
+		if sealedChat.String, err = db.crypt.encrypt(strconv.FormatInt(update.ChatID, 10)); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO addresses (request_id, address, phone, chat_id, created_at, key_version)
+		SELECT ?, ?, ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM requests WHERE id = ? AND status = ?)
+	`, update.RequestID, sealedAddress, sealedPhone, sealedChat, time.Now(), db.keyVersion, update.RequestID, st.status)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("request changed while updating, please try again")
+	}
+
+	return tx.Commit()
+}
+
+// GetContact retrieves and decrypts the address and phone for a request.
+// It returns sql.ErrNoRows if none was saved.
+func (db *DB) GetContact(ctx context.Context, requestID int64) (*models.Contact, error) {
+	return db.getContact(ctx, db.conn, requestID)
+}
+
+func (db *DB) getContact(ctx context.Context, conn querier, requestID int64) (*models.Contact, error) {
+	contact := models.Contact{RequestID: requestID}
+	var sealedAddress string
+	var sealedPhone, sealedChat sql.NullString
+
+	err := conn.QueryRowContext(ctx,
+		`SELECT address, phone, chat_id, created_at FROM addresses WHERE request_id = ?`, requestID,
+	).Scan(&sealedAddress, &sealedPhone, &sealedChat, &contact.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if contact.Address, err = db.crypt.decrypt(sealedAddress); err != nil {
+		return nil, err
+	}
+	if sealedPhone.Valid {
+		if contact.Phone, err = db.crypt.decrypt(sealedPhone.String); err != nil {
+			return nil, err
+		}
+	}
+	if sealedChat.Valid {
+		chat, err := db.crypt.decrypt(sealedChat.String)
+		if err != nil {
+			return nil, err
+		}
+		if contact.ChatID, err = strconv.ParseInt(chat, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid family chat ID: %w", err)
+		}
+	}
+
+	return &contact, nil
+}
+
+// AddVolunteer registers someone who joined the volunteer chat, not yet
+// approved. If they were seen before, only their names are updated, so
+// rejoining keeps their approval, role, ban and notes.
+func (db *DB) AddVolunteer(ctx context.Context, telegramID int64, username, displayName string) error {
+	_, err := db.conn.ExecContext(ctx, `
+		INSERT INTO volunteers (telegram_id, username, display_name, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (telegram_id) DO UPDATE SET
+			username = COALESCE(NULLIF(excluded.username, ''), username),
+			display_name = COALESCE(NULLIF(excluded.display_name, ''), display_name)
+	`, telegramID, username, displayName, time.Now())
 	return err
 }
 
 // IsVolunteerApproved checks if a user is an approved volunteer
-func (db *DB) IsVolunteerApproved(telegramID int64) (bool, error) {
+func (db *DB) IsVolunteerApproved(ctx context.Context, telegramID int64) (bool, error) {
 	var isApproved bool
-	err := db.conn.QueryRow(
+	err := db.conn.QueryRowContext(ctx,
 		`SELECT is_approved FROM volunteers WHERE telegram_id = ?`, telegramID,
 	).Scan(&isApproved)
 	if err == sql.ErrNoRows {
@@ -290,10 +964,19 @@ func (db *DB) IsVolunteerApproved(telegramID int64) (bool, error) {
 	return isApproved, err
 }
 
+// IsVolunteer checks if a user has joined as a volunteer, approved or not
+func (db *DB) IsVolunteer(ctx context.Context, telegramID int64) (bool, error) {
+	var count int
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM volunteers WHERE telegram_id = ?`, telegramID,
+	).Scan(&count)
+	return count > 0, err
+}
+
 // IsCoordinator checks if a user is a coordinator
-func (db *DB) IsCoordinator(telegramID int64) (bool, error) {
+func (db *DB) IsCoordinator(ctx context.Context, telegramID int64) (bool, error) {
 	var isCoordinator bool
-	err := db.conn.QueryRow(
+	err := db.conn.QueryRowContext(ctx,
 		`SELECT is_coordinator FROM volunteers WHERE telegram_id = ?`, telegramID,
 	).Scan(&isCoordinator)
 	if err == sql.ErrNoRows {
@@ -302,17 +985,35 @@ func (db *DB) IsCoordinator(telegramID int64) (bool, error) {
 	return isCoordinator, err
 }
 
-// PurgeOldRequests deletes delivered requests older than the specified duration
-func (db *DB) PurgeOldRequests(olderThan time.Duration) (int64, error) {
+// PurgeOldRequests deletes delivered and cancelled requests older than the specified duration
+func (db *DB) PurgeOldRequests(ctx context.Context, olderThan time.Duration) (int64, error) {
 	cutoff := time.Now().Add(-olderThan)
-	result, err := db.conn.Exec(
-		`DELETE FROM requests WHERE status = ? AND delivered_at < ?`,
-		models.StatusDelivered, cutoff,
-	)
+	const purgeable = `(status = ? AND delivered_at < ?) OR (status = ? AND updated_at < ?)`
+	args := []any{models.StatusDelivered, cutoff, models.StatusCancelled, cutoff}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
-	return result.RowsAffected()
+	defer tx.Rollback()
+
+	for _, table := range []string{"request_items", "substitutions"} {
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE request_id IN (SELECT id FROM requests WHERE `+purgeable+`)`, args...)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM requests WHERE `+purgeable, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
 }
 
 // Close closes the database connection
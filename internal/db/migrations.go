@@ -0,0 +1,340 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// migration is one numbered, forward-only schema change. Migrations are
+// applied in order, each in its own transaction, and recorded in
+// schema_migrations. Never edit or renumber a migration once it has shipped;
+// add a new one instead.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{1, "initial schema", execSQL(`
+	CREATE TABLE IF NOT EXISTS requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		original_text TEXT NOT NULL,
+		translated_text TEXT,
+		budget TEXT,
+		zone TEXT,
+		status TEXT NOT NULL DEFAULT 'new',
+		claimed_by INTEGER,
+		claimed_by_name TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		delivered_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS volunteers (
+		telegram_id INTEGER PRIMARY KEY,
+		username TEXT,
+		display_name TEXT,
+		is_approved INTEGER DEFAULT 0,
+		is_coordinator INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS addresses (
+		request_id INTEGER PRIMARY KEY,
+		address TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (request_id) REFERENCES requests(id)
+	);
+
+	CREATE TABLE IF NOT EXISTS meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_requests_status ON requests(status);
+	CREATE INDEX IF NOT EXISTS idx_requests_claimed_by ON requests(claimed_by);
+	`)},
+
+	// Databases created before migrations existed may already have these
+	{2, "key_version columns", func(tx *sql.Tx) error {
+		for _, table := range []string{"requests", "addresses"} {
+			if err := ensureColumn(tx, table, "key_version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+
+	{3, "card_message_id", execSQL(`ALTER TABLE requests ADD COLUMN card_message_id INTEGER`)},
+
+	{4, "addresses.phone", execSQL(`ALTER TABLE addresses ADD COLUMN phone TEXT`)},
+
+	{5, "request_items", execSQL(`
+	CREATE TABLE request_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		request_id INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		original TEXT,
+		quantity TEXT,
+		unit TEXT,
+		category TEXT NOT NULL,
+		notes TEXT,
+		uncertain INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (request_id) REFERENCES requests(id)
+	);
+
+	CREATE INDEX idx_request_items_request_id ON request_items(request_id, position);
+
+	ALTER TABLE requests ADD COLUMN notes TEXT;
+	`)},
+
+	{6, "request_items.status", execSQL(`ALTER TABLE request_items ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'`)},
+
+	{7, "substitutions", execSQL(`
+	ALTER TABLE requests ADD COLUMN created_by INTEGER;
+
+	CREATE TABLE substitutions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		request_id INTEGER NOT NULL,
+		item_id INTEGER,
+		item TEXT NOT NULL,
+		replacement TEXT NOT NULL,
+		proposed_by INTEGER NOT NULL,
+		proposed_by_name TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		decided_by INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		decided_at DATETIME,
+		FOREIGN KEY (request_id) REFERENCES requests(id)
+	);
+
+	CREATE INDEX idx_substitutions_request_id ON substitutions(request_id);
+	`)},
+
+	{8, "addresses.chat_id", execSQL(`ALTER TABLE addresses ADD COLUMN chat_id TEXT`)},
+
+	{9, "family intake", execSQL(`
+	ALTER TABLE requests ADD COLUMN delivery_window TEXT;
+
+	CREATE TABLE sessions (
+		chat_id INTEGER PRIMARY KEY,
+		flow TEXT NOT NULL,
+		step TEXT NOT NULL,
+		data TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		key_version INTEGER NOT NULL DEFAULT 1
+	);
+	`)},
+
+	{10, "requests.needs_translation", execSQL(`ALTER TABLE requests ADD COLUMN needs_translation INTEGER NOT NULL DEFAULT 0`)},
+
+	{11, "update queue", execSQL(`
+	CREATE TABLE updates (
+		update_id INTEGER PRIMARY KEY,
+		payload TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		received_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		finished_at DATETIME,
+		key_version INTEGER NOT NULL DEFAULT 1
+	);
+
+	CREATE INDEX idx_updates_status ON updates(status);
+	`)},
+
+	{12, "audit events", execSQL(`
+	CREATE TABLE audit_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		target_id INTEGER,
+		details TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX idx_audit_events_target_id ON audit_events(target_id);
+	`)},
+
+	{13, "volunteer bans and notes", execSQL(`
+	ALTER TABLE volunteers ADD COLUMN is_banned INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE volunteers ADD COLUMN notes TEXT;
+	`)},
+
+	// Untranslated requests used to keep a plaintext copy of the family's
+	// Spanish as their translation; original_text already has it, encrypted
+	{14, "clear untranslated Spanish", execSQL(`UPDATE requests SET translated_text = '' WHERE needs_translation = 1`)},
+}
+
+func execSQL(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// MigrationResult describes a migration that was (or, in a dry run, would be) applied
+type MigrationResult struct {
+	Version int
+	Name    string
+}
+
+// Migrate applies pending migrations to the database at dbPath without
+// opening it for normal use. With dryRun, all pending migrations run in a
+// single transaction that is rolled back, so an upgrade can be checked
+// against a copy of production without changing it.
+func Migrate(dbPath string, dryRun bool) ([]MigrationResult, error) {
+	conn, err := sql.Open("sqlite3", dataSource(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	if dryRun {
+		return dryRunMigrations(conn)
+	}
+	return applyMigrations(conn)
+}
+
+func (db *DB) migrate() error {
+	applied, err := applyMigrations(db.conn)
+	for _, m := range applied {
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	return err
+}
+
+func applyMigrations(conn *sql.DB) ([]MigrationResult, error) {
+	pending, err := pendingMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []MigrationResult
+	for _, m := range pending {
+		tx, err := conn.Begin()
+		if err != nil {
+			return applied, err
+		}
+
+		if err := runMigration(tx, m); err != nil {
+			tx.Rollback()
+			return applied, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		applied = append(applied, MigrationResult{Version: m.version, Name: m.name})
+	}
+
+	return applied, nil
+}
+
+func dryRunMigrations(conn *sql.DB) ([]MigrationResult, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pending, err := pendingMigrations(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []MigrationResult
+	for _, m := range pending {
+		if err := runMigration(tx, m); err != nil {
+			return applied, err
+		}
+		applied = append(applied, MigrationResult{Version: m.version, Name: m.name})
+	}
+
+	return applied, nil
+}
+
+func runMigration(tx *sql.Tx, m migration) error {
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+	}
+
+	_, err := tx.Exec(
+		`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name,
+	)
+	if err != nil {
+		return fmt.Errorf("migration %d (%s): failed to record: %w", m.version, m.name, err)
+	}
+
+	return nil
+}
+
+// pendingMigrations returns migrations not yet recorded in schema_migrations
+func pendingMigrations(conn execer) ([]migration, error) {
+	_, err := conn.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		if !applied[m.version] {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, nil
+}
+
+// ensureColumn adds a column to an existing table if it is missing
+func ensureColumn(conn execer, table, column, definition string) error {
+	rows, err := conn.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = conn.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
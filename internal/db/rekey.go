@@ -0,0 +1,270 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrRekeyInProgress is returned by New while a key rotation is unfinished
+var ErrRekeyInProgress = errors.New("key rotation in progress; finish it with cmd/rekey before starting the bot")
+
+// rekeyBatchSize is the number of rows re-encrypted per transaction
+const rekeyBatchSize = 200
+
+// sensitiveTable lists a table's encrypted columns and the key that
+// identifies its rows. All encrypted columns in a row share its key_version.
+type sensitiveTable struct {
+	table    string
+	idColumn string
+	columns  []string
+}
+
+var sensitiveTables = []sensitiveTable{
+	{table: "requests", idColumn: "id", columns: []string{"original_text"}},
+	{table: "addresses", idColumn: "request_id", columns: []string{"address", "phone", "chat_id"}},
+	{table: "sessions", idColumn: "chat_id", columns: []string{"data"}},
+	{table: "updates", idColumn: "update_id", columns: []string{"payload"}},
+}
+
+// RekeyResult summarizes a completed key rotation
+type RekeyResult struct {
+	FromVersion int
+	ToVersion   int
+	Rows        int64 // Rows re-encrypted by this run
+	Resumed     bool  // True if this run continued an interrupted rotation
+}
+
+// Rekey re-encrypts every sensitive column from oldKey to newKey.
+//
+// Rows are rewritten in small transactions and tagged with the new key
+// version, so an interrupted rotation can be resumed by running Rekey
+// again with the same keys. The bot refuses to start until it finishes.
+func Rekey(dbPath, oldKey, newKey string) (*RekeyResult, error) {
+	if oldKey == newKey {
+		return nil, fmt.Errorf("new key must differ from the old key")
+	}
+
+	oldCrypt, err := newCrypter(oldKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid old key: %w", err)
+	}
+	newCrypt, err := newCrypter(newKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid new key: %w", err)
+	}
+
+	conn, err := sql.Open("sqlite3", dataSource(dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	db := &DB{conn: conn, crypt: oldCrypt}
+	if err := db.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	check, ok, err := getMeta(conn, "key_check")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("database has no key check; start the bot once before rotating keys")
+	}
+	if plain, err := oldCrypt.decrypt(check); err != nil || plain != keyCheckPlaintext {
+		return nil, fmt.Errorf("old key cannot decrypt this database: %w", ErrWrongKey)
+	}
+
+	fromVersion, err := currentKeyVersion(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RekeyResult{FromVersion: fromVersion}
+
+	// Record the target version and a check value for the new key before
+	// touching any rows, so a resumed run can confirm it has the same key.
+	pendingVersion, pending, err := getMeta(conn, "rekey_version")
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		pendingCheck, _, err := getMeta(conn, "rekey_check")
+		if err != nil {
+			return nil, err
+		}
+		if plain, err := newCrypt.decrypt(pendingCheck); err != nil || plain != keyCheckPlaintext {
+			return nil, fmt.Errorf("an unfinished rotation used a different new key: %w", ErrWrongKey)
+		}
+		if result.ToVersion, err = strconv.Atoi(pendingVersion); err != nil {
+			return nil, fmt.Errorf("invalid rekey_version %q: %w", pendingVersion, err)
+		}
+		result.Resumed = true
+	} else {
+		result.ToVersion = fromVersion + 1
+		newCheck, err := newCrypt.encrypt(keyCheckPlaintext)
+		if err != nil {
+			return nil, err
+		}
+		if err := setMeta(conn, "rekey_check", newCheck); err != nil {
+			return nil, err
+		}
+		if err := setMeta(conn, "rekey_version", strconv.Itoa(result.ToVersion)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range sensitiveTables {
+		for {
+			n, err := rekeyBatch(conn, oldCrypt, newCrypt, result.ToVersion, t)
+			if err != nil {
+				return nil, fmt.Errorf("failed to re-encrypt %s: %w", t.table, err)
+			}
+			result.Rows += n
+			if n == 0 {
+				break
+			}
+		}
+	}
+
+	// Switch the database over to the new key
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	newCheck, _, err := getMeta(tx, "rekey_check")
+	if err != nil {
+		return nil, err
+	}
+	if err := setMeta(tx, "key_check", newCheck); err != nil {
+		return nil, err
+	}
+	if err := setMeta(tx, "key_version", strconv.Itoa(result.ToVersion)); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM meta WHERE key IN ('rekey_check', 'rekey_version')`); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// rekeyBatch re-encrypts up to rekeyBatchSize rows not yet at toVersion
+// and returns how many it rewrote.
+func rekeyBatch(conn *sql.DB, oldCrypt, newCrypt *crypter, toVersion int, t sensitiveTable) (int64, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(fmt.Sprintf(
+		`SELECT %s, %s FROM %s WHERE key_version != ? LIMIT ?`,
+		t.idColumn, strings.Join(t.columns, ", "), t.table,
+	), toVersion, rekeyBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	values := make(map[int64][]sql.NullString)
+	for rows.Next() {
+		var id int64
+		row := make([]sql.NullString, len(t.columns))
+		dest := []any{&id}
+		for i := range row {
+			dest = append(dest, &row[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		values[id] = row
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var set []string
+	for _, column := range t.columns {
+		set = append(set, column+" = ?")
+	}
+	update := fmt.Sprintf(`UPDATE %s SET %s, key_version = ? WHERE %s = ?`,
+		t.table, strings.Join(set, ", "), t.idColumn)
+
+	for id, row := range values {
+		var args []any
+		for _, value := range row {
+			if !value.Valid {
+				args = append(args, nil)
+				continue
+			}
+
+			plain := value.String
+			if isEncrypted(plain) {
+				if plain, err = oldCrypt.decrypt(plain); err != nil {
+					return 0, fmt.Errorf("row %d: %w", id, err)
+				}
+			}
+
+			sealed, err := newCrypt.encrypt(plain)
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, sealed)
+		}
+
+		if _, err := tx.Exec(update, append(args, toVersion, id)...); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return int64(len(values)), nil
+}
+
+// currentKeyVersion returns the version of the key the data is encrypted with
+func currentKeyVersion(conn execer) (int, error) {
+	value, ok, err := getMeta(conn, "key_version")
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 1, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid key_version %q: %w", value, err)
+	}
+	return version, nil
+}
+
+func getMeta(conn execer, key string) (string, bool, error) {
+	var value string
+	err := conn.QueryRow(`SELECT value FROM meta WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func setMeta(conn execer, key, value string) error {
+	_, err := conn.Exec(`INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)`, key, value)
+	return err
+}
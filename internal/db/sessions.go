@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// GetSession returns a chat's conversation in progress, or sql.ErrNoRows
+// if there is none
+func (db *DB) GetSession(ctx context.Context, chatID int64) (*models.Session, error) {
+	session := models.Session{ChatID: chatID}
+	var sealed string
+
+	err := db.conn.QueryRowContext(ctx,
+		`SELECT flow, step, data, updated_at FROM sessions WHERE chat_id = ?`, chatID,
+	).Scan(&session.Flow, &session.Step, &sealed, &session.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := db.crypt.decrypt(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(data), &session.Data); err != nil {
+		return nil, err
+	}
+	if session.Data == nil {
+		session.Data = make(map[string]string)
+	}
+
+	return &session, nil
+}
+
+// SaveSession creates or replaces a chat's conversation. The collected
+// answers may include addresses and phones, so they are encrypted.
+func (db *DB) SaveSession(ctx context.Context, session *models.Session) error {
+	data, err := json.Marshal(session.Data)
+	if err != nil {
+		return err
+	}
+	sealed, err := db.crypt.encrypt(string(data))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.conn.ExecContext(ctx,
+		`INSERT OR REPLACE INTO sessions (chat_id, flow, step, data, updated_at, key_version) VALUES (?, ?, ?, ?, ?, ?)`,
+		session.ChatID, session.Flow, session.Step, sealed, time.Now(), db.keyVersion,
+	)
+	return err
+}
+
+// DeleteSession ends a chat's conversation
+func (db *DB) DeleteSession(ctx context.Context, chatID int64) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM sessions WHERE chat_id = ?`, chatID)
+	return err
+}
+
+// PurgeStaleSessions deletes conversations abandoned for longer than olderThan
+func (db *DB) PurgeStaleSessions(ctx context.Context, olderThan time.Duration) (int64, error) {
+	result, err := db.conn.ExecContext(ctx, `DELETE FROM sessions WHERE updated_at < ?`, time.Now().Add(-olderThan))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// ErrSubstitutionStale is returned when answering a substitution whose
+// volunteer no longer has the request, e.g. after a release or delivery
+var ErrSubstitutionStale = errors.New("the volunteer who asked is no longer shopping this request, so this substitution no longer applies")
+
+// CreateSubstitution records a volunteer's proposed substitution, pending
+// the family's answer. Only the volunteer who claimed the request can
+// propose one.
+func (db *DB) CreateSubstitution(ctx context.Context, sub *models.Substitution) (*models.Substitution, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	st, err := loadRequestState(ctx, tx, sub.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	if st.claimedBy != sub.ProposedBy || (st.status != models.StatusClaimed && st.status != models.StatusShopping) {
+		return nil, fmt.Errorf("you don't have this request claimed")
+	}
+
+	var itemID sql.NullInt64
+	if sub.ItemID != 0 {
+		itemID = sql.NullInt64{Int64: sub.ItemID, Valid: true}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO substitutions (request_id, item_id, item, replacement, proposed_by, proposed_by_name, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, sub.RequestID, itemID, sub.Item, sub.Replacement, sub.ProposedBy, sub.ProposedByName, models.SubstitutionPending)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return db.GetSubstitution(ctx, id)
+}
+
+// GetSubstitution retrieves a substitution by ID
+func (db *DB) GetSubstitution(ctx context.Context, id int64) (*models.Substitution, error) {
+	return scanSubstitution(db.conn.QueryRowContext(ctx, substitutionColumns+` WHERE id = ?`, id))
+}
+
+// GetSubstitutions returns every substitution proposed for a request, oldest first
+func (db *DB) GetSubstitutions(ctx context.Context, requestID int64) ([]models.Substitution, error) {
+	rows, err := db.conn.QueryContext(ctx, substitutionColumns+` WHERE request_id = ? ORDER BY id`, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []models.Substitution
+	for rows.Next() {
+		sub, err := scanSubstitution(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// DecideSubstitution records the family's answer to a pending
+// substitution. An approved substitution marks its item as substituted.
+// It fails with ErrSubstitutionStale once the volunteer who proposed it no
+// longer has the request claimed.
+func (db *DB) DecideSubstitution(ctx context.Context, id int64, coordinatorID int64, approved bool) (*models.Substitution, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sub, err := scanSubstitution(tx.QueryRowContext(ctx, substitutionColumns+` WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	if sub.Status != models.SubstitutionPending {
+		return nil, fmt.Errorf("substitution was already answered")
+	}
+
+	st, err := loadRequestState(ctx, tx, sub.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	if st.claimedBy != sub.ProposedBy || (st.status != models.StatusClaimed && st.status != models.StatusShopping) {
+		return nil, ErrSubstitutionStale
+	}
+
+	status := models.SubstitutionRejected
+	if approved {
+		status = models.SubstitutionApproved
+	}
+
+	// Checked against the request too, in case it was released meanwhile
+	result, err := tx.ExecContext(ctx, `
+		UPDATE substitutions SET status = ?, decided_by = ?, decided_at = ?
+		WHERE id = ? AND status = ? AND EXISTS (
+			SELECT 1 FROM requests WHERE id = ? AND status = ? AND claimed_by = ?
+		)
+	`, status, coordinatorID, time.Now(), id, models.SubstitutionPending, sub.RequestID, st.status, sub.ProposedBy)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, fmt.Errorf("request changed while updating, please try again")
+	}
+
+	if approved {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE request_items SET status = ?
+			WHERE id = (SELECT item_id FROM substitutions WHERE id = ?)
+		`, models.ItemSubstituted, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return db.GetSubstitution(ctx, id)
+}
+
+const substitutionColumns = `
+	SELECT id, request_id, item_id, item, replacement, proposed_by, COALESCE(proposed_by_name, ''),
+	       status, decided_by, created_at, decided_at
+	FROM substitutions`
+
+// scanner is satisfied by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSubstitution(row scanner) (*models.Substitution, error) {
+	var sub models.Substitution
+	var itemID, decidedBy sql.NullInt64
+	var decidedAt sql.NullTime
+
+	err := row.Scan(
+		&sub.ID, &sub.RequestID, &itemID, &sub.Item, &sub.Replacement, &sub.ProposedBy, &sub.ProposedByName,
+		&sub.Status, &decidedBy, &sub.CreatedAt, &decidedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("substitution not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sub.ItemID = itemID.Int64
+	sub.DecidedBy = decidedBy.Int64
+	if decidedAt.Valid {
+		sub.DecidedAt = &decidedAt.Time
+	}
+	return &sub, nil
+}
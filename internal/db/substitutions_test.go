@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// proposeSubstitution claims a request for volunteerID and proposes a
+// substitution for its first item
+func proposeSubstitution(t *testing.T, db *DB, volunteerID int64) *models.Substitution {
+	t.Helper()
+	ctx := context.Background()
+	id := postedRequest(t, db, models.RequestItem{Name: "Queso fresco", Category: models.CategoryDairy})
+	if err := db.ClaimRequest(ctx, id, volunteerID, "Volunteer"); err != nil {
+		t.Fatal(err)
+	}
+	req, err := db.GetRequest(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := db.CreateSubstitution(ctx, &models.Substitution{
+		RequestID:   id,
+		ItemID:      req.Items[0].ID,
+		Item:        "Queso fresco",
+		Replacement: "Queso blanco",
+		ProposedBy:  volunteerID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func TestDecideSubstitution(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	sub := proposeSubstitution(t, db, 100)
+
+	decided, err := db.DecideSubstitution(ctx, sub.ID, 1, true)
+	if err != nil {
+		t.Fatalf("DecideSubstitution: %v", err)
+	}
+	if decided.Status != models.SubstitutionApproved || decided.DecidedBy != 1 {
+		t.Errorf("got status %s decided by %d", decided.Status, decided.DecidedBy)
+	}
+
+	req, err := db.GetRequest(ctx, sub.RequestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Items[0].Status != models.ItemSubstituted {
+		t.Errorf("item status = %s, want %s", req.Items[0].Status, models.ItemSubstituted)
+	}
+
+	if _, err := db.DecideSubstitution(ctx, sub.ID, 1, false); err == nil {
+		t.Error("answered the same substitution twice")
+	}
+}
+
+func TestDecideSubstitutionStale(t *testing.T) {
+	tests := []struct {
+		name  string
+		after func(db *DB, sub *models.Substitution) error
+	}{
+		{"released", func(db *DB, sub *models.Substitution) error {
+			return db.ReleaseClaim(context.Background(), sub.RequestID, sub.ProposedBy, false)
+		}},
+		{"claimed by someone else", func(db *DB, sub *models.Substitution) error {
+			ctx := context.Background()
+			if err := db.ReleaseClaim(ctx, sub.RequestID, sub.ProposedBy, false); err != nil {
+				return err
+			}
+			return db.ClaimRequest(ctx, sub.RequestID, 200, "Another volunteer")
+		}},
+		{"delivered", func(db *DB, sub *models.Substitution) error {
+			return db.CompleteRequest(context.Background(), sub.RequestID, sub.ProposedBy)
+		}},
+		{"cancelled", func(db *DB, sub *models.Substitution) error {
+			return db.CancelRequest(context.Background(), sub.RequestID)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			ctx := context.Background()
+			sub := proposeSubstitution(t, db, 100)
+			if err := tt.after(db, sub); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := db.DecideSubstitution(ctx, sub.ID, 1, true); !errors.Is(err, ErrSubstitutionStale) {
+				t.Fatalf("DecideSubstitution error = %v, want ErrSubstitutionStale", err)
+			}
+
+			got, err := db.GetSubstitution(ctx, sub.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != models.SubstitutionPending {
+				t.Errorf("stale substitution was recorded as %s", got.Status)
+			}
+			req, err := db.GetRequest(ctx, sub.RequestID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Items[0].Status == models.ItemSubstituted {
+				t.Error("stale approval marked the item substituted")
+			}
+		})
+	}
+}
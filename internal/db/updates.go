@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// Update queue statuses
+const (
+	updatePending = "pending"
+	updateDone    = "done"
+	updateFailed  = "failed"
+)
+
+// EnqueueUpdate saves an incoming update before it is processed. It
+// reports false if the update was seen before, so Telegram's retries are
+// only handled once, even across restarts. The payload holds family
+// messages, so it is encrypted.
+func (db *DB) EnqueueUpdate(ctx context.Context, updateID int, payload []byte) (bool, error) {
+	sealed, err := db.crypt.encrypt(string(payload))
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.conn.ExecContext(ctx,
+		`INSERT OR IGNORE INTO updates (update_id, payload, status, received_at, key_version) VALUES (?, ?, ?, ?, ?)`,
+		updateID, sealed, updatePending, time.Now(), db.keyVersion,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// PendingUpdates returns updates that were saved but never finished, oldest
+// first, for replay at startup
+func (db *DB) PendingUpdates(ctx context.Context) ([]models.QueuedUpdate, error) {
+	rows, err := db.conn.QueryContext(ctx,
+		`SELECT update_id, payload, attempts, received_at FROM updates WHERE status = ? ORDER BY update_id`,
+		updatePending,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var updates []models.QueuedUpdate
+	for rows.Next() {
+		var u models.QueuedUpdate
+		var sealed string
+		if err := rows.Scan(&u.UpdateID, &sealed, &u.Attempts, &u.ReceivedAt); err != nil {
+			return nil, err
+		}
+		payload, err := db.crypt.decrypt(sealed)
+		if err != nil {
+			return nil, err
+		}
+		u.Payload = []byte(payload)
+		updates = append(updates, u)
+	}
+	return updates, rows.Err()
+}
+
+// StartUpdate counts an attempt at processing an update
+func (db *DB) StartUpdate(ctx context.Context, updateID int) error {
+	_, err := db.conn.ExecContext(ctx, `UPDATE updates SET attempts = attempts + 1 WHERE update_id = ?`, updateID)
+	return err
+}
+
+// FinishUpdate marks an update processed and drops its payload, keeping
+// only the ID to reject duplicates
+func (db *DB) FinishUpdate(ctx context.Context, updateID int) error {
+	return db.closeUpdate(ctx, updateID, updateDone)
+}
+
+// FailUpdate gives up on an update that keeps failing, e.g. one that
+// crashes the bot every time it is replayed
+func (db *DB) FailUpdate(ctx context.Context, updateID int) error {
+	return db.closeUpdate(ctx, updateID, updateFailed)
+}
+
+func (db *DB) closeUpdate(ctx context.Context, updateID int, status string) error {
+	_, err := db.conn.ExecContext(ctx,
+		`UPDATE updates SET status = ?, payload = NULL, finished_at = ? WHERE update_id = ?`,
+		status, time.Now(), updateID,
+	)
+	return err
+}
+
+// PurgeFinishedUpdates forgets updates finished more than olderThan ago.
+// Telegram stops retrying an update after a day, so IDs only need to be
+// kept a little longer than that to catch duplicates.
+func (db *DB) PurgeFinishedUpdates(ctx context.Context, olderThan time.Duration) (int64, error) {
+	result, err := db.conn.ExecContext(ctx,
+		`DELETE FROM updates WHERE status != ? AND finished_at < ?`, updatePending, time.Now().Add(-olderThan),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+// ErrVolunteerBanned is returned when approving a banned volunteer
+var ErrVolunteerBanned = errors.New("volunteer is banned")
+
+const volunteerColumns = `
+	SELECT telegram_id, COALESCE(username, ''), COALESCE(display_name, ''), is_approved, is_coordinator,
+	       is_banned, COALESCE(notes, ''), created_at
+	FROM volunteers`
+
+// SetCoordinator grants or removes a user's coordinator role and records an
+// audit event for actorID. It reports false, and records nothing, if the
+// user already had that role.
+func (db *DB) SetCoordinator(ctx context.Context, telegramID int64, isCoordinator bool, actorID int64) (bool, error) {
+	action := models.AuditDemote
+	if isCoordinator {
+		action = models.AuditPromote
+	}
+	return db.setVolunteer(ctx, models.AuditEvent{ActorID: actorID, Action: action, TargetID: telegramID},
+		"is_coordinator", isCoordinator, isCoordinator)
+}
+
+// ApproveVolunteer lets a user claim requests. It fails with
+// ErrVolunteerBanned for banned users and reports false if they were
+// already approved.
+func (db *DB) ApproveVolunteer(ctx context.Context, telegramID int64, actorID int64) (bool, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	v, err := scanVolunteer(tx.QueryRowContext(ctx, volunteerColumns+` WHERE telegram_id = ?`, telegramID))
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if v != nil && v.IsBanned {
+		return false, ErrVolunteerBanned
+	}
+
+	event := models.AuditEvent{ActorID: actorID, Action: models.AuditApprove, TargetID: telegramID}
+	changed, err := updateVolunteer(ctx, tx, event, "is_approved", true, true)
+	if err != nil || !changed {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RevokeVolunteer removes a volunteer's approval. It reports false if they
+// weren't approved. Their claims are left for the caller to release.
+func (db *DB) RevokeVolunteer(ctx context.Context, telegramID int64, actorID int64) (bool, error) {
+	return db.setVolunteer(ctx, models.AuditEvent{ActorID: actorID, Action: models.AuditRevoke, TargetID: telegramID},
+		"is_approved", false, false)
+}
+
+// BanVolunteer bans a user and removes their approval, so they can't be
+// approved again until unbanned. Users who never joined can be banned in
+// advance. It reports false if they were already banned.
+func (db *DB) BanVolunteer(ctx context.Context, telegramID int64, reason string, actorID int64) (bool, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	event := models.AuditEvent{ActorID: actorID, Action: models.AuditBan, TargetID: telegramID, Details: reason}
+	changed, err := updateVolunteer(ctx, tx, event, "is_banned", true, true)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE volunteers SET is_approved = 0 WHERE telegram_id = ?`, telegramID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// UnbanVolunteer lifts a ban. The user still needs approving again. It
+// reports false if they weren't banned.
+func (db *DB) UnbanVolunteer(ctx context.Context, telegramID int64, actorID int64) (bool, error) {
+	return db.setVolunteer(ctx, models.AuditEvent{ActorID: actorID, Action: models.AuditUnban, TargetID: telegramID},
+		"is_banned", false, false)
+}
+
+// SetVolunteerNotes replaces coordinators' notes on a volunteer; empty
+// notes clears them. It reports false if the notes were unchanged.
+func (db *DB) SetVolunteerNotes(ctx context.Context, telegramID int64, notes string, actorID int64) (bool, error) {
+	value := sql.NullString{String: notes, Valid: notes != ""}
+	return db.setVolunteer(ctx, models.AuditEvent{ActorID: actorID, Action: models.AuditNote, TargetID: telegramID, Details: notes},
+		"notes", value, notes != "")
+}
+
+// setVolunteer runs updateVolunteer in its own transaction
+func (db *DB) setVolunteer(ctx context.Context, event models.AuditEvent, column string, value any, create bool) (bool, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	changed, err := updateVolunteer(ctx, tx, event, column, value, create)
+	if err != nil || !changed {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// updateVolunteer sets one column of event.TargetID's profile and records
+// event. It reports false, recording nothing, if the column already had
+// that value. With create, users who never joined get a profile first.
+func updateVolunteer(ctx context.Context, tx *sql.Tx, event models.AuditEvent, column string, value any, create bool) (bool, error) {
+	if create {
+		_, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO volunteers (telegram_id, created_at) VALUES (?, ?)`, event.TargetID, time.Now(),
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx,
+		fmt.Sprintf(`UPDATE volunteers SET %s = ? WHERE telegram_id = ? AND %s IS NOT ?`, column, column),
+		value, event.TargetID, value,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	return true, recordAudit(ctx, tx, event)
+}
+
+// GetVolunteers returns everyone who joined or was given a role, oldest first
+func (db *DB) GetVolunteers(ctx context.Context) ([]models.Volunteer, error) {
+	return db.queryVolunteers(ctx, volunteerColumns+` ORDER BY created_at, telegram_id`)
+}
+
+// GetCoordinators returns the users given the coordinator role in the
+// database, oldest first. Coordinators configured at startup aren't included.
+func (db *DB) GetCoordinators(ctx context.Context) ([]models.Volunteer, error) {
+	return db.queryVolunteers(ctx, volunteerColumns+` WHERE is_coordinator = 1 ORDER BY created_at, telegram_id`)
+}
+
+// GetVolunteer retrieves a volunteer's profile. It returns sql.ErrNoRows if
+// they never joined or were never given a role.
+func (db *DB) GetVolunteer(ctx context.Context, telegramID int64) (*models.Volunteer, error) {
+	return scanVolunteer(db.conn.QueryRowContext(ctx, volunteerColumns+` WHERE telegram_id = ?`, telegramID))
+}
+
+func (db *DB) queryVolunteers(ctx context.Context, query string, args ...any) ([]models.Volunteer, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var volunteers []models.Volunteer
+	for rows.Next() {
+		v, err := scanVolunteer(rows)
+		if err != nil {
+			return nil, err
+		}
+		volunteers = append(volunteers, *v)
+	}
+	return volunteers, rows.Err()
+}
+
+func scanVolunteer(row scanner) (*models.Volunteer, error) {
+	var v models.Volunteer
+	err := row.Scan(&v.TelegramID, &v.Username, &v.DisplayName, &v.IsApproved, &v.IsCoordinator,
+		&v.IsBanned, &v.Notes, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
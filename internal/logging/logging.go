@@ -0,0 +1,54 @@
+// Package logging sets up the structured logger shared by the bot and
+// decides what may be written to logs. Logs end up on the host and in
+// its log storage, so family messages, translations, addresses, phone
+// numbers and names are redacted unless debug logging is explicitly on.
+//
+// Log request and user IDs as structured fields ("request_id", "user_id",
+// "chat_id") and wrap any free text in Text.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+)
+
+var debug atomic.Bool
+
+type Options struct {
+	Debug bool // Log debug messages and sensitive text in full
+	JSON  bool // One JSON object per line instead of key=value text
+}
+
+// Setup installs the default slog logger, writing to w
+func Setup(w io.Writer, opts Options) {
+	debug.Store(opts.Debug)
+
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if opts.Debug {
+		handlerOpts.Level = slog.LevelDebug
+	}
+
+	var handler slog.Handler = slog.NewTextHandler(w, handlerOpts)
+	if opts.JSON {
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// Sensitive is text that could identify a family or volunteer. It logs
+// as its length only, unless debug logging is on.
+type Sensitive string
+
+func (s Sensitive) LogValue() slog.Value {
+	if debug.Load() {
+		return slog.StringValue(string(s))
+	}
+	return slog.StringValue(fmt.Sprintf("[redacted %d bytes]", len(s)))
+}
+
+// Text is a log attribute for sensitive text
+func Text(key, value string) slog.Attr {
+	return slog.Any(key, Sensitive(value))
+}
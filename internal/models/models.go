@@ -1,46 +1,232 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type RequestStatus string
 
 const (
-	StatusNew       RequestStatus = "new"
-	StatusPosted    RequestStatus = "posted"
-	StatusClaimed   RequestStatus = "claimed"
-	StatusShopping  RequestStatus = "shopping"
-	StatusDelivered RequestStatus = "delivered"
-	StatusCancelled RequestStatus = "cancelled"
+	StatusNew           RequestStatus = "new"
+	StatusPendingReview RequestStatus = "pending_review" // Waiting for a coordinator to approve posting
+	StatusPosted        RequestStatus = "posted"
+	StatusClaimed       RequestStatus = "claimed"
+	StatusShopping      RequestStatus = "shopping"
+	StatusDelivered     RequestStatus = "delivered"
+	StatusCancelled     RequestStatus = "cancelled"
 )
 
+// transitions is the request lifecycle:
+//
+//	new → posted → claimed → shopping → delivered
+//
+// Requests that need a coordinator's approval go new → pending_review →
+// posted. A claim may skip shopping and go straight to delivered, and a
+// claimed or shopping request can be released back to posted. Any status
+// that is not terminal may also move to cancelled.
+var transitions = map[RequestStatus][]RequestStatus{
+	StatusNew:           {StatusPosted, StatusPendingReview},
+	StatusPendingReview: {StatusPosted},
+	StatusPosted:        {StatusClaimed},
+	StatusClaimed:       {StatusShopping, StatusDelivered, StatusPosted},
+	StatusShopping:      {StatusDelivered, StatusPosted},
+}
+
+// IsTerminal reports whether a request in this status is finished
+func (s RequestStatus) IsTerminal() bool {
+	return s == StatusDelivered || s == StatusCancelled
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving from s to next
+func (s RequestStatus) CanTransitionTo(next RequestStatus) bool {
+	if s.IsTerminal() {
+		return false
+	}
+	if next == StatusCancelled {
+		return true
+	}
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError is returned when a status change is not allowed
+type TransitionError struct {
+	From RequestStatus
+	To   RequestStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("request is %s and can't be marked %s", e.From, e.To)
+}
+
 // Request represents a grocery request from a family
 type Request struct {
-	ID            int64
-	OriginalText  string        // Spanish text as received
-	TranslatedText string       // Formatted English shopping list
-	Budget        string        // e.g., "$100 cash"
-	Zone          string        // Neighborhood/area
-	Status        RequestStatus
-	ClaimedBy     int64         // Volunteer's Telegram user ID
-	ClaimedByName string        // Volunteer's display name
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	DeliveredAt   *time.Time
+	ID             int64
+	OriginalText   string // Spanish text as received
+	TranslatedText string // Formatted English shopping list
+	Items          []RequestItem
+	Notes          string // Special instructions from the family, in English
+	Budget         string // e.g., "$100 cash"
+	Zone           string // Neighborhood/area
+	Status         RequestStatus
+	ClaimedBy      int64  // Volunteer's Telegram user ID
+	ClaimedByName  string // Volunteer's display name
+	CardMessageID  int    // Telegram message ID of the card in the volunteer chat
+	CreatedBy      int64  // Coordinator who entered the request and is in touch with the family
+	DeliveryWindow string // When the family can receive groceries, as they put it
+	// Translation failed, so TranslatedText is empty and OriginalText is shown instead
+	NeedsTranslation bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeliveredAt      *time.Time
+}
+
+// Shopping list categories, in the order lists are shown
+const (
+	CategoryMeat      = "MEAT"
+	CategoryProduce   = "PRODUCE"
+	CategoryDairy     = "DAIRY"
+	CategoryPantry    = "PANTRY"
+	CategoryHousehold = "HOUSEHOLD"
+	CategoryOther     = "OTHER"
+)
+
+// Categories lists every category in display order
+var Categories = []string{
+	CategoryMeat, CategoryProduce, CategoryDairy, CategoryPantry, CategoryHousehold, CategoryOther,
+}
+
+// RequestItem is one line of a request's shopping list
+type RequestItem struct {
+	ID        int64
+	RequestID int64
+	Position  int    // Order within the request, from 0
+	Name      string // English name
+	Original  string // As the family wrote it, in Spanish
+	Quantity  string // e.g. "2", "1/2"
+	Unit      string // e.g. "lbs", "pack"
+	Category  string // One of Categories
+	Notes     string // Explanation of regional items, brand, etc.
+	Uncertain bool   // The translator wasn't sure what the family meant
+	Status    ItemStatus
+}
+
+// ItemStatus is where an item stands on the volunteer's checklist
+type ItemStatus string
+
+const (
+	ItemPending     ItemStatus = "pending"
+	ItemFound       ItemStatus = "found"
+	ItemSubstituted ItemStatus = "substituted"
+	ItemUnavailable ItemStatus = "unavailable"
+)
+
+// Next is the status a checklist button moves an item to:
+// pending → found → substituted → unavailable → pending
+func (s ItemStatus) Next() ItemStatus {
+	switch s {
+	case ItemPending:
+		return ItemFound
+	case ItemFound:
+		return ItemSubstituted
+	case ItemSubstituted:
+		return ItemUnavailable
+	default:
+		return ItemPending
+	}
 }
 
-// Volunteer represents an approved volunteer
+// SubstitutionStatus is where a proposed substitution stands with the family
+type SubstitutionStatus string
+
+const (
+	SubstitutionPending  SubstitutionStatus = "pending"
+	SubstitutionApproved SubstitutionStatus = "approved"
+	SubstitutionRejected SubstitutionStatus = "rejected"
+)
+
+// Substitution is a replacement a volunteer proposed for an out-of-stock
+// item. Substitutions are kept on the request as a record of what was bought.
+type Substitution struct {
+	ID             int64
+	RequestID      int64
+	ItemID         int64  // Matching request item, or 0 if none matched
+	Item           string // What the family asked for
+	Replacement    string // What the volunteer proposes instead
+	ProposedBy     int64  // Volunteer's Telegram user ID
+	ProposedByName string
+	Status         SubstitutionStatus
+	DecidedBy      int64 // Coordinator who relayed the family's answer
+	CreatedAt      time.Time
+	DecidedAt      *time.Time
+}
+
+// Volunteer represents someone who joined the volunteer chat or was given
+// a role, approved or not
 type Volunteer struct {
-	TelegramID   int64
-	Username     string
-	DisplayName  string
-	IsApproved   bool
+	TelegramID    int64
+	Username      string
+	DisplayName   string
+	IsApproved    bool
 	IsCoordinator bool
-	CreatedAt    time.Time
+	IsBanned      bool   // Can't be approved again until unbanned
+	Notes         string // Coordinators' notes, e.g. "has car, speaks Spanish"
+	CreatedAt     time.Time
 }
 
-// Address is stored separately and deleted after delivery
-type Address struct {
+// AuditAction is a kind of administrative change
+type AuditAction string
+
+const (
+	AuditPromote AuditAction = "promote" // Made a coordinator
+	AuditDemote  AuditAction = "demote"  // Coordinator role removed
+	AuditApprove AuditAction = "approve" // Approved as a volunteer
+	AuditRevoke  AuditAction = "revoke"  // Volunteer approval removed
+	AuditBan     AuditAction = "ban"
+	AuditUnban   AuditAction = "unban"
+	AuditNote    AuditAction = "note" // Volunteer notes changed; Details has the new text
+)
+
+// AuditEvent records who made an administrative change and to whom
+type AuditEvent struct {
+	ID        int64
+	ActorID   int64 // Telegram user ID of who made the change
+	Action    AuditAction
+	TargetID  int64  // Telegram user ID the change applies to
+	Details   string // Optional free text, e.g. a reason
+	CreatedAt time.Time
+}
+
+// Contact is the family's delivery address, phone and Telegram chat. It is
+// stored encrypted, separately from the request, and deleted after delivery.
+type Contact struct {
 	RequestID int64
 	Address   string
+	Phone     string
+	ChatID    int64 // Family's own Telegram chat, if they're linked; 0 otherwise
 	CreatedAt time.Time
 }
+
+// Session is a multi-step conversation with one chat, such as a family's
+// intake. It is stored encrypted so a restart doesn't lose the answers.
+type Session struct {
+	ChatID    int64
+	Flow      string            // Which conversation this is, e.g. "intake"
+	Step      string            // Where in the flow the chat is
+	Data      map[string]string // Answers collected so far
+	UpdatedAt time.Time
+}
+
+// QueuedUpdate is a Telegram update saved before it is processed, so it
+// survives a crash or restart
+type QueuedUpdate struct {
+	UpdateID   int
+	Payload    []byte // The update as Telegram sent it, in JSON
+	Attempts   int    // Times processing has started
+	ReceivedAt time.Time
+}
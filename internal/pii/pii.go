@@ -0,0 +1,185 @@
+// Package pii redacts personal information that must never reach the
+// public volunteer chat. It is a deterministic second line of defense
+// behind the translation prompt, which is asked to do the same.
+package pii
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Kind is the type of personal information found
+type Kind string
+
+const (
+	Phone   Kind = "phone"
+	Email   Kind = "email"
+	Address Kind = "address"
+	ZIP     Kind = "zip"
+	Name    Kind = "name"
+)
+
+// Finding is one piece of personal information removed from a text
+type Finding struct {
+	Kind Kind
+	Text string // The text that was redacted
+}
+
+// streetSuffixes are the US street types (and Spanish equivalents) that end
+// an address such as "1234 W Maryland Ave"
+const streetSuffixes = `st|street|ave|avenue|av|blvd|boulevard|rd|road|dr|drive|ln|lane|ct|court|way|pl|place|` +
+	`pkwy|parkway|cir|circle|ter|terrace|hwy|highway|trl|trail|sq|square|calle|avenida`
+
+// Apartment or unit after an address: "Apt 3", "#204", "depto 5B"
+const unitPattern = `(?:,?\s*(?:apt|apartment|unit|suite|ste|depto|departamento|dpto|#)\.?\s*#?[0-9a-z-]+)?`
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+
+	// Ten to thirteen digits with the separators people type: 651-555-1234,
+	// (612) 555 1234, +52 1 55 1234 5678, 6515551234. Checked by digit count.
+	phonePattern = regexp.MustCompile(`\+?\(?\d[\d\s().-]{8,18}\d`)
+
+	// Seven-digit local numbers need a separator so quantities don't match
+	localPhonePattern = regexp.MustCompile(`\b\d{3}[-.]\d{4}\b`)
+
+	// "1234 W Maryland Ave Apt 3", "45 Oak St."
+	streetPattern = regexp.MustCompile(`(?i)\b\d{2,6}\s+(?:[nsew]\.?\s+)?(?:[\p{L}0-9.'-]+\s+){0,4}?(?:` +
+		streetSuffixes + `)\b\.?` + unitPattern)
+
+	// "calle Robert 456", "avenida Cesar Chavez #12"
+	spanishStreetPattern = regexp.MustCompile(`(?i)\b(?:calle|avenida|av\.)\s+(?:[\p{L}.'-]+\s+){1,4}#?\d{1,6}\b` + unitPattern)
+
+	// Minnesota ZIP codes (550xx-567xx), only after a state, city, "zip" or
+	// a redacted street, so request numbers like "#55101" aren't mistaken
+	// for them
+	zipPattern = regexp.MustCompile(`(?i)(?:\b(?:mn|minn\.?|minnesota|minneapolis|st\.?\s+paul|saint\s+paul|zip(?:\s+code)?|` +
+		`c[oó]digo\s+postal|c\.p\.)|\[address\])[\s,:]*((?:55\d|56[0-7])\d{2}(?:-\d{4})?)\b`)
+
+	// A list bullet or number at the start of a line, as in "• 100 Grand Ave bars"
+	listItemPattern = regexp.MustCompile(`^\s*(?:[•*-]|\d{1,2}[.)])\s*$`)
+
+	// "me llamo María López", "my name is Ana Ruiz": keep the first name
+	introPattern = regexp.MustCompile(`(?i:me llamo|mi nombre es|my name is|soy la señora|soy el señor|nombre:|name:)\s+` +
+		`\p{Lu}[\p{L}'-]+((?:\s+(?:de\s+)?\p{Lu}[\p{L}'-]+)+)`)
+
+	// "Sra. López", "Señor Hernández García", "Mrs. Smith"
+	honorificPattern = regexp.MustCompile(`(?:\b(?:Sra|Sr|Srta|Mrs|Mr|Ms)\.?|\b(?:Señora|Señor|Señorita|Doña|Don))` +
+		`(\s+\p{Lu}[\p{L}'-]+(?:\s+\p{Lu}[\p{L}'-]+)*)`)
+)
+
+// Scrub returns text with personal information replaced by placeholders
+// such as "[phone]", and what it replaced. First names are kept; surnames
+// are redacted.
+func Scrub(text string) (string, []Finding) {
+	var findings []Finding
+
+	// keep sees the match and what comes before it on its line
+	replace := func(pattern *regexp.Regexp, kind Kind, keep func(before, match string) bool) {
+		var sb strings.Builder
+		last := 0
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			match := text[loc[0]:loc[1]]
+			before := text[strings.LastIndex(text[:loc[0]], "\n")+1 : loc[0]]
+			if keep != nil && keep(before, match) {
+				continue
+			}
+			findings = append(findings, Finding{Kind: kind, Text: strings.TrimSpace(match)})
+			sb.WriteString(text[last:loc[0]])
+			sb.WriteString("[" + string(kind) + "]")
+			last = loc[1]
+		}
+		sb.WriteString(text[last:])
+		text = sb.String()
+	}
+
+	// Emails first, so their digits aren't mistaken for phone numbers
+	replace(emailPattern, Email, nil)
+	replace(phonePattern, Phone, func(_, match string) bool {
+		digits := countDigits(match)
+		return digits < 10 || digits > 13
+	})
+	replace(localPhonePattern, Phone, nil)
+	replace(streetPattern, Address, isProduct)
+	replace(spanishStreetPattern, Address, nil)
+	text = replaceGroup(zipPattern, text, func(zip string) string {
+		findings = append(findings, Finding{Kind: ZIP, Text: zip})
+		return "[" + string(ZIP) + "]"
+	})
+
+	// Names: redact only the surname part of the match
+	for _, pattern := range []*regexp.Regexp{introPattern, honorificPattern} {
+		text = replaceGroup(pattern, text, func(surname string) string {
+			findings = append(findings, Finding{Kind: Name, Text: strings.TrimSpace(surname)})
+			return " [" + string(Name) + "]"
+		})
+	}
+
+	return text, findings
+}
+
+// replaceGroup replaces the first capture group of every match of pattern
+func replaceGroup(pattern *regexp.Regexp, text string, replacement func(group string) string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[2], loc[3]
+		if start < 0 {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(replacement(text[start:end]))
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// groceryUnits are words that show a "number ... suffix" match is a
+// shopping list line, like "12 pack Dr Pepper" or "18 ct tortillas",
+// rather than an address
+var groceryUnits = map[string]bool{
+	"pack": true, "packs": true, "lb": true, "lbs": true, "oz": true, "fl": true, "can": true, "cans": true,
+	"ct": true, "count": true, "dozen": true,
+	"box": true, "boxes": true, "bag": true, "bags": true, "bottle": true, "bottles": true,
+	"paquete": true, "paquetes": true, "libra": true, "libras": true, "lata": true, "latas": true,
+	"bolsa": true, "bolsas": true, "caja": true, "cajas": true, "botella": true, "botellas": true,
+}
+
+// isProduct reports whether a street-like match is a shopping list line:
+// a quantity with a unit, or an item on a list such as "• 100 Grand Ave
+// bars" (a candy bar)
+func isProduct(before, match string) bool {
+	return hasGroceryUnit(match) || listItemPattern.MatchString(before)
+}
+
+// hasGroceryUnit reports whether the word after a match's number is a
+// unit. Only that word counts, so "123 Oak Ct" is still an address even
+// though "ct" is also a unit.
+func hasGroceryUnit(match string) bool {
+	words := strings.Fields(strings.ToLower(match))
+	return len(words) > 1 && groceryUnits[strings.Trim(words[1], ".,")]
+}
+
+func countDigits(s string) int {
+	count := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			count++
+		}
+	}
+	return count
+}
+
+// Kinds lists the distinct kinds of personal information in findings
+func Kinds(findings []Finding) []Kind {
+	var kinds []Kind
+	seen := make(map[Kind]bool)
+	for _, f := range findings {
+		if !seen[f.Kind] {
+			seen[f.Kind] = true
+			kinds = append(kinds, f.Kind)
+		}
+	}
+	return kinds
+}
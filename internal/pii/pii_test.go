@@ -0,0 +1,69 @@
+package pii
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		want  string
+		kinds []Kind
+	}{
+		// Personal information families include in their messages
+		{"phone with dashes", "Mi número es 651-555-1234, gracias", "Mi número es [phone], gracias", []Kind{Phone}},
+		{"phone with parentheses", "Pueden llamar al (612) 555 1234 después de las 5", "Pueden llamar al [phone] después de las 5", []Kind{Phone}},
+		{"mexican phone", "Mi esposo: +52 1 55 1234 5678", "Mi esposo: [phone]", []Kind{Phone}},
+		{"local phone", "o marque 555-1234", "o marque [phone]", []Kind{Phone}},
+		{"email", "mi correo es maria.lopez@gmail.com", "mi correo es [email]", []Kind{Email}},
+		{"street with unit", "Vivo en 1234 W Maryland Ave Apt 3, St Paul", "Vivo en [address], St Paul", []Kind{Address}},
+		{"street with depto", "La dirección es 45 Oak St. depto 5B", "La dirección es [address]", []Kind{Address}},
+		{"court", "Estoy en 789 Pine Ct", "Estoy en [address]", []Kind{Address}},
+		{"spanish street", "calle Robert 456 #12", "[address]", []Kind{Address}},
+		{"zip", "St Paul MN 55106", "St Paul MN [zip]", []Kind{ZIP}},
+		{"zip after city", "Minneapolis, 55404-1234", "Minneapolis, [zip]", []Kind{ZIP}},
+		{"zip labelled", "código postal: 55117", "código postal: [zip]", []Kind{ZIP}},
+		{"street on a list line", "• Entregar en 123 Main St", "• Entregar en [address]", []Kind{Address}},
+		{"introduction", "Hola, me llamo María López Hernández", "Hola, me llamo María [name]", []Kind{Name}},
+		{"honorific", "Es para la Sra. Hernández García", "Es para la Sra. [name]", []Kind{Name}},
+		{
+			"whole message",
+			"Soy la señora Rosa Méndez, 2 libras de arroz. Entregar en 1510 Payne Ave, 55130. Tel 651-555-0199",
+			"Soy la señora Rosa [name], 2 libras de arroz. Entregar en [address], [zip]. Tel [phone]",
+			[]Kind{Phone, Address, ZIP, Name},
+		},
+
+		// Grocery lines that look like addresses or phone numbers
+		{"ct eggs", "12 ct eggs", "12 ct eggs", nil},
+		{"ct tortillas", "18 ct tortillas", "18 ct tortillas", nil},
+		{"count", "24 count Dr Pepper", "24 count Dr Pepper", nil},
+		{"dozen", "12 dozen eggs", "12 dozen eggs", nil},
+		{"pack", "12 pack Dr Pepper", "12 pack Dr Pepper", nil},
+		{"fluid ounces", "20 fl oz Dr Pepper", "20 fl oz Dr Pepper", nil},
+		{"spanish units", "2 libras de arroz, 3 latas de frijoles, 1 galón de leche", "2 libras de arroz, 3 latas de frijoles, 1 galón de leche", nil},
+		{"budget", "$80 en efectivo", "$80 en efectivo", nil},
+		{"packages", "10 paquetes de tortillas de maíz", "10 paquetes de tortillas de maíz", nil},
+		{"first name only", "Gracias, María", "Gracias, María", nil},
+		{"product on a list", "• 100 Grand Ave bars", "• 100 Grand Ave bars", nil},
+		{"product on a card", "SNACKS\n• 100 Grand Ave bars - 2", "SNACKS\n• 100 Grand Ave bars - 2", nil},
+
+		// Request numbers in the ZIP code range
+		{"request number", "✅ Request #55101 delivered!", "✅ Request #55101 delivered!", nil},
+		{"claim command", "Reply /claim 56012 to take this request", "Reply /claim 56012 to take this request", nil},
+		{"bare number", "Request 55101 was cancelled", "Request 55101 was cancelled", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, findings := Scrub(tt.text)
+			if got != tt.want {
+				t.Errorf("Scrub(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if kinds := Kinds(findings); !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("Scrub(%q) found %v, want %v", tt.text, kinds, tt.kinds)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// chain tries each translator in turn until one succeeds
+type chain struct {
+	translators []Translator
+}
+
+// NewChain creates a translator that falls back to the next translator
+// (e.g. a cheaper model, or a local server) when one fails. If they all
+// fail the last error is returned, and the caller decides what to post.
+// If the context is cancelled it stops there and returns the context's error.
+func NewChain(translators ...Translator) Translator {
+	if len(translators) == 1 {
+		return translators[0]
+	}
+	return &chain{translators: translators}
+}
+
+// TranslateRequest takes Spanish grocery text and returns formatted English with PII extracted
+func (c *chain) TranslateRequest(ctx context.Context, spanishText string) (*TranslationResult, error) {
+	var result *TranslationResult
+	var err error
+	for i, t := range c.translators {
+		result, err = t.TranslateRequest(ctx, spanishText)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if i < len(c.translators)-1 {
+			slog.Warn("Translator failed, trying the next one", "translator", i+1, "err", err)
+		}
+	}
+	return result, err
+}
+
+// TranslateToSpanish translates a short message for a family into Spanish
+func (c *chain) TranslateToSpanish(ctx context.Context, englishText string) (string, error) {
+	var spanish string
+	var err error
+	for i, t := range c.translators {
+		spanish, err = t.TranslateToSpanish(ctx, englishText)
+		if err == nil {
+			return spanish, nil
+		}
+		if ctx.Err() != nil {
+			return spanish, ctx.Err()
+		}
+		if i < len(c.translators)-1 {
+			slog.Warn("Translator failed, trying the next one", "translator", i+1, "err", err)
+		}
+	}
+	return spanish, err
+}
+
+// FormatRequest creates the final formatted message for volunteers
+func (c *chain) FormatRequest(requestID int64, zone string, budget string, translatedText string) string {
+	return FormatRequest(requestID, zone, budget, translatedText)
+}
+
+// Close releases resources
+func (c *chain) Close() error {
+	var errs []error
+	for _, t := range c.translators {
+		errs = append(errs, t.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,83 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubTranslator returns a fixed result and error, and counts its calls
+type stubTranslator struct {
+	text  string
+	err   error
+	calls int
+}
+
+func (s *stubTranslator) TranslateRequest(ctx context.Context, spanishText string) (*TranslationResult, error) {
+	s.calls++
+	return &TranslationResult{CleanedText: s.text}, s.err
+}
+
+func (s *stubTranslator) TranslateToSpanish(ctx context.Context, englishText string) (string, error) {
+	s.calls++
+	return s.text, s.err
+}
+
+func (s *stubTranslator) FormatRequest(requestID int64, zone string, budget string, translatedText string) string {
+	return FormatRequest(requestID, zone, budget, translatedText)
+}
+
+func (s *stubTranslator) Close() error { return nil }
+
+func TestChainFallsBack(t *testing.T) {
+	primary := &stubTranslator{text: "3 chayotes", err: errors.New("rate limited")}
+	fallback := &stubTranslator{text: "3 chayote squash"}
+	tr := NewChain(primary, fallback)
+
+	result, err := tr.TranslateRequest(context.Background(), "3 chayotes")
+	if err != nil || result.CleanedText != "3 chayote squash" {
+		t.Errorf("TranslateRequest = %q, %v; want the fallback's translation", result.CleanedText, err)
+	}
+
+	spanish, err := tr.TranslateToSpanish(context.Background(), "On my way")
+	if err != nil || spanish != "3 chayote squash" {
+		t.Errorf("TranslateToSpanish = %q, %v; want the fallback's translation", spanish, err)
+	}
+}
+
+func TestChainAllFail(t *testing.T) {
+	last := errors.New("server down")
+	tr := NewChain(&stubTranslator{err: errors.New("rate limited")}, &stubTranslator{text: "3 chayotes", err: last})
+
+	result, err := tr.TranslateRequest(context.Background(), "3 chayotes")
+	if !errors.Is(err, last) {
+		t.Errorf("TranslateRequest error = %v, want %v", err, last)
+	}
+	if result.CleanedText != "3 chayotes" {
+		t.Errorf("CleanedText = %q, want the original text", result.CleanedText)
+	}
+
+	if _, err := tr.TranslateToSpanish(context.Background(), "On my way"); !errors.Is(err, last) {
+		t.Errorf("TranslateToSpanish error = %v, want %v", err, last)
+	}
+}
+
+func TestChainCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	primary := &stubTranslator{text: "3 chayotes", err: context.Canceled}
+	fallback := &stubTranslator{text: "3 chayote squash"}
+	tr := NewChain(primary, fallback)
+
+	// A cancelled request fails rather than passing off the original text as a translation
+	if _, err := tr.TranslateRequest(ctx, "3 chayotes"); !errors.Is(err, context.Canceled) {
+		t.Errorf("TranslateRequest error = %v, want context.Canceled", err)
+	}
+	if _, err := tr.TranslateToSpanish(ctx, "On my way"); !errors.Is(err, context.Canceled) {
+		t.Errorf("TranslateToSpanish error = %v, want context.Canceled", err)
+	}
+	if fallback.calls != 0 {
+		t.Errorf("fallback called %d times after cancellation", fallback.calls)
+	}
+}
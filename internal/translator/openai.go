@@ -0,0 +1,382 @@
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/centromex/grocery-bot/internal/logging"
+	"github.com/centromex/grocery-bot/internal/models"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
+
+	// Ollama's OpenAI-compatible endpoint; llama.cpp server uses http://localhost:8080/v1
+	defaultLocalBaseURL = "http://localhost:11434/v1"
+	defaultLocalModel   = "llama3.2:3b"
+
+	defaultTimeout = 30 * time.Second
+
+	// Backoff between retries doubles from retryBaseDelay up to retryMaxDelay
+	retryBaseDelay = 1 * time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// chatTranslator translates via an OpenAI-compatible /chat/completions endpoint
+type chatTranslator struct {
+	name       string // For logs and errors
+	baseURL    string
+	apiKey     string
+	model      string
+	client     *http.Client
+	requireKey bool // Fail without calling out when no API key is configured
+	maxRetries int
+	prompt     *promptTemplate
+}
+
+// NewOpenAI creates a translator that uses OpenAI's chat completions API
+func NewOpenAI(cfg Config) Translator {
+	return &chatTranslator{
+		name:       "OpenAI",
+		baseURL:    withDefault(cfg.BaseURL, defaultOpenAIBaseURL),
+		apiKey:     cfg.OpenAIKey,
+		model:      withDefault(cfg.Model, defaultOpenAIModel),
+		client:     httpClient(cfg),
+		requireKey: true,
+		maxRetries: cfg.MaxRetries,
+		prompt:     newPromptTemplate(cfg.PromptPath),
+	}
+}
+
+// NewLocal creates a translator that uses an OpenAI-compatible server we
+// run ourselves (llama.cpp server, Ollama), so family texts never leave
+// our infrastructure.
+func NewLocal(cfg Config) Translator {
+	return &chatTranslator{
+		name:       "local LLM",
+		baseURL:    withDefault(cfg.BaseURL, defaultLocalBaseURL),
+		apiKey:     cfg.OpenAIKey,
+		model:      withDefault(cfg.Model, defaultLocalModel),
+		client:     httpClient(cfg),
+		maxRetries: cfg.MaxRetries,
+		prompt:     newPromptTemplate(cfg.PromptPath),
+	}
+}
+
+func withDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func httpClient(cfg Config) *http.Client {
+	if cfg.HTTPClient != nil {
+		return cfg.HTTPClient
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+type openAIRequest struct {
+	Model       string    `json:"model"`
+	Messages    []message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float64   `json:"temperature"`
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message message `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// TranslateRequest takes Spanish grocery text and returns formatted English with PII extracted
+func (t *chatTranslator) TranslateRequest(ctx context.Context, spanishText string) (*TranslationResult, error) {
+	// If no API key, return Spanish as-is
+	if t.requireKey && t.apiKey == "" {
+		return &TranslationResult{CleanedText: spanishText}, fmt.Errorf("no %s API key configured", t.name)
+	}
+
+	prompt, err := t.prompt.render(spanishText)
+	if err != nil {
+		return &TranslationResult{CleanedText: spanishText}, err
+	}
+
+	content, err := t.complete(ctx,
+		"You are a helpful translator for a mutual aid organization. Translate grocery lists accurately and answer in the JSON format requested.",
+		prompt,
+	)
+	if err != nil {
+		return &TranslationResult{CleanedText: spanishText}, err
+	}
+
+	// Parse JSON response
+	var result struct {
+		Budget      string     `json:"budget"`
+		Items       []jsonItem `json:"items"`
+		Notes       string     `json:"notes"`
+		Translation string     `json:"translation"` // Older prompts return a formatted list instead of items
+		Address     string     `json:"address"`
+		Phone       string     `json:"phone"`
+	}
+
+	if err := json.Unmarshal([]byte(stripCodeFence(content)), &result); err != nil {
+		slog.Warn("Failed to parse translation JSON, using as plain text", "backend", t.name, "err", err)
+		// Fallback to using content directly if not JSON
+		return &TranslationResult{CleanedText: content}, nil
+	}
+
+	translated := &TranslationResult{
+		CleanedText: result.Translation,
+		Notes:       strings.TrimSpace(result.Notes),
+		Budget:      result.Budget,
+		Address:     result.Address,
+		Phone:       result.Phone,
+	}
+	for _, item := range result.Items {
+		if strings.TrimSpace(item.Name) == "" {
+			continue
+		}
+		translated.Items = append(translated.Items, models.RequestItem{
+			Name:      strings.TrimSpace(item.Name),
+			Original:  strings.TrimSpace(item.Original),
+			Quantity:  strings.TrimSpace(string(item.Quantity)),
+			Unit:      strings.TrimSpace(item.Unit),
+			Category:  normalizeCategory(item.Category),
+			Notes:     strings.TrimSpace(item.Notes),
+			Uncertain: item.Uncertain,
+		})
+	}
+	if len(translated.Items) > 0 {
+		translated.CleanedText = FormatShoppingList(translated.Items, translated.Notes)
+	}
+
+	slog.Info("Translation successful", "backend", t.name, "chars", len(spanishText), "items", len(translated.Items),
+		"address", result.Address != "", "phone", result.Phone != "")
+
+	return translated, nil
+}
+
+// TranslateToSpanish translates a short message for a family into Spanish
+func (t *chatTranslator) TranslateToSpanish(ctx context.Context, englishText string) (string, error) {
+	if t.requireKey && t.apiKey == "" {
+		return "", fmt.Errorf("no %s API key configured", t.name)
+	}
+
+	return t.complete(ctx,
+		"You are a helpful translator for a mutual aid organization. Translate messages for families from English into simple, friendly Latin American Spanish. Reply with only the translation.",
+		englishText,
+	)
+}
+
+// jsonItem is a shopping list item as the prompt asks the model to return it
+type jsonItem struct {
+	Name      string     `json:"name"`
+	Original  string     `json:"original"`
+	Quantity  flexString `json:"quantity"`
+	Unit      string     `json:"unit"`
+	Category  string     `json:"category"`
+	Notes     string     `json:"notes"`
+	Uncertain bool       `json:"uncertain"`
+}
+
+// flexString accepts a JSON string or number, since models often return
+// quantities as numbers even when asked for strings
+type flexString string
+
+func (f *flexString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*f = flexString(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*f = flexString(n.String())
+	return nil
+}
+
+// complete sends a system and user message to the chat completions
+// endpoint and returns the trimmed reply
+func (t *chatTranslator) complete(ctx context.Context, system, prompt string) (string, error) {
+	reqBody := openAIRequest{
+		Model: t.model,
+		Messages: []message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+		MaxTokens:   1500, // Item lists in JSON are verbose
+		Temperature: 0.3,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var body []byte
+	for attempt := 0; ; attempt++ {
+		body, err = t.post(ctx, jsonData)
+
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) || attempt >= t.maxRetries {
+			break
+		}
+
+		delay := retryDelay(attempt, retryable.retryAfter)
+		slog.Warn("Translation API call failed, retrying", "backend", t.name, "err", err,
+			"delay", delay, "attempt", attempt+1, "max_retries", t.maxRetries)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var openAIResp openAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		slog.Error("Failed to parse API response", "backend", t.name, "err", err)
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if openAIResp.Error != nil {
+		slog.Error("API error", "backend", t.name, "message", openAIResp.Error.Message)
+		return "", fmt.Errorf("%s API error: %s", t.name, openAIResp.Error.Message)
+	}
+
+	if len(openAIResp.Choices) == 0 {
+		slog.Warn("No choices in API response", "backend", t.name)
+		return "", fmt.Errorf("no translation returned from %s", t.name)
+	}
+
+	content := strings.TrimSpace(openAIResp.Choices[0].Message.Content)
+	if content == "" {
+		slog.Warn("Empty translation", "backend", t.name)
+		return "", fmt.Errorf("empty translation from %s", t.name)
+	}
+
+	return content, nil
+}
+
+// retryableError is a failure worth trying again: a timeout or network
+// error, a rate limit, or a server error
+type retryableError struct {
+	err        error
+	retryAfter time.Duration // From the Retry-After header, if the server sent one
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// post sends one chat completions request and returns the response body.
+// Other error statuses are returned as a body for complete to report.
+func (t *chatTranslator) post(ctx context.Context, jsonData []byte) ([]byte, error) {
+	url := strings.TrimSuffix(t.baseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		slog.Warn("API request failed", "backend", t.name, "err", err)
+		return nil, &retryableError{err: fmt.Errorf("API request failed: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.Warn("Failed to read API response", "backend", t.name, "err", err)
+		return nil, &retryableError{err: fmt.Errorf("failed to read response: %w", err)}
+	}
+
+	// The body holds the family's list, address and phone
+	slog.Debug("API response", "backend", t.name, "status", resp.StatusCode, logging.Text("body", string(body)))
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, &retryableError{
+			err:        fmt.Errorf("%s API returned %s", t.name, resp.Status),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	return body, nil
+}
+
+// retryDelay is how long to wait before retry number attempt+1: the
+// server's Retry-After if it gave one, otherwise exponential backoff
+func retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay == 0 {
+		delay = retryBaseDelay << attempt
+	}
+	if delay > retryMaxDelay || delay < 0 {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds, or returns 0
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// stripCodeFence removes a ```json ... ``` wrapper, which smaller local
+// models often put around JSON answers
+func stripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimPrefix(content, "json")
+	content = strings.TrimSuffix(content, "```")
+	return strings.TrimSpace(content)
+}
+
+// FormatRequest creates the final formatted message for volunteers
+func (t *chatTranslator) FormatRequest(requestID int64, zone string, budget string, translatedText string) string {
+	return FormatRequest(requestID, zone, budget, translatedText)
+}
+
+// Close releases resources
+func (t *chatTranslator) Close() error {
+	return nil
+}
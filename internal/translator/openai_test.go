@@ -0,0 +1,196 @@
+package translator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// backends are the chat translators under test, built against a test server
+var backends = []struct {
+	name string
+	new  func(Config) Translator
+	key  string
+}{
+	{"openai", NewOpenAI, "sk-test"},
+	{"local", NewLocal, ""},
+}
+
+// chatServer answers /chat/completions with the given status and body,
+// and checks the request the translator sent
+func chatServer(t *testing.T, key string, status int, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("got %s %s, want POST /v1/chat/completions", r.Method, r.URL.Path)
+		}
+
+		wantAuth := ""
+		if key != "" {
+			wantAuth = "Bearer " + key
+		}
+		if got := r.Header.Get("Authorization"); got != wantAuth {
+			t.Errorf("Authorization = %q, want %q", got, wantAuth)
+		}
+
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if req.Model != "test-model" || len(req.Messages) != 2 {
+			t.Errorf("got model %q with %d messages", req.Model, len(req.Messages))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// reply wraps content as a chat completions response
+func reply(t *testing.T, content string) string {
+	t.Helper()
+	var resp openAIResponse
+	resp.Choices = append(resp.Choices, struct {
+		Message message `json:"message"`
+	}{Message: message{Role: "assistant", Content: content}})
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func newTestTranslator(t *testing.T, newTranslator func(Config) Translator, key string, server *httptest.Server) Translator {
+	t.Helper()
+	return newTranslator(Config{
+		OpenAIKey:  key,
+		BaseURL:    server.URL + "/v1",
+		Model:      "test-model",
+		HTTPClient: server.Client(),
+	})
+}
+
+func TestTranslateRequest(t *testing.T) {
+	content := "```json\n" + `{
+		"budget": "$40",
+		"items": [
+			{"name": "Chayote squash", "original": "chayotes", "quantity": 3, "category": "produce"},
+			{"name": "Corn tortillas", "original": "tortillas de maíz", "quantity": "2", "unit": "packs", "category": "bakery"},
+			{"name": " ", "quantity": "1"}
+		],
+		"notes": "Ripe avocados please",
+		"address": "123 Calle Luna",
+		"phone": "555-123-4567"
+	}` + "\n```"
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			server := chatServer(t, backend.key, http.StatusOK, reply(t, content))
+			tr := newTestTranslator(t, backend.new, backend.key, server)
+
+			result, err := tr.TranslateRequest(context.Background(), "3 chayotes y 2 paquetes de tortillas de maíz")
+			if err != nil {
+				t.Fatalf("TranslateRequest: %v", err)
+			}
+
+			if len(result.Items) != 2 {
+				t.Fatalf("got %d items, want 2: %+v", len(result.Items), result.Items)
+			}
+			if item := result.Items[0]; item.Name != "Chayote squash" || item.Quantity != "3" || item.Category != "PRODUCE" {
+				t.Errorf("first item = %+v", item)
+			}
+			if item := result.Items[1]; item.Quantity != "2" || item.Unit != "packs" {
+				t.Errorf("second item = %+v", item)
+			}
+			if result.Budget != "$40" || result.Notes != "Ripe avocados please" {
+				t.Errorf("budget %q, notes %q", result.Budget, result.Notes)
+			}
+			if result.Address != "123 Calle Luna" || result.Phone != "555-123-4567" {
+				t.Errorf("address %q, phone %q", result.Address, result.Phone)
+			}
+			if result.CleanedText != FormatShoppingList(result.Items, result.Notes) {
+				t.Errorf("CleanedText = %q", result.CleanedText)
+			}
+		})
+	}
+}
+
+func TestTranslateRequestPlainText(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			server := chatServer(t, backend.key, http.StatusOK, reply(t, "3 chayote squash"))
+			tr := newTestTranslator(t, backend.new, backend.key, server)
+
+			// A reply that isn't the JSON we asked for is still a translation
+			result, err := tr.TranslateRequest(context.Background(), "3 chayotes")
+			if err != nil {
+				t.Fatalf("TranslateRequest: %v", err)
+			}
+			if result.CleanedText != "3 chayote squash" || len(result.Items) != 0 {
+				t.Errorf("got %+v", result)
+			}
+		})
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"malformed body", http.StatusOK, `{"choices": [`, "failed to parse response"},
+		{"not JSON", http.StatusOK, "<html>Bad Gateway</html>", "failed to parse response"},
+		{"no choices", http.StatusOK, `{"choices": []}`, "no translation returned"},
+		{"empty content", http.StatusOK, `{"choices": [{"message": {"role": "assistant", "content": "  "}}]}`, "empty translation"},
+		{"bad request", http.StatusBadRequest, `{"error": {"message": "model not found"}}`, "model not found"},
+		{"unauthorized", http.StatusUnauthorized, `{"error": {"message": "invalid api key"}}`, "invalid api key"},
+		{"rate limited", http.StatusTooManyRequests, `{"error": {"message": "slow down"}}`, "429 Too Many Requests"},
+		{"server error", http.StatusServiceUnavailable, "upstream unavailable", "503 Service Unavailable"},
+	}
+
+	for _, backend := range backends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				server := chatServer(t, backend.key, tt.status, tt.body)
+				tr := newTestTranslator(t, backend.new, backend.key, server)
+
+				result, err := tr.TranslateRequest(context.Background(), "3 chayotes")
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("TranslateRequest error = %v, want %q", err, tt.wantErr)
+				}
+				// The translator hands back the original text alongside the error
+				if result == nil || result.CleanedText != "3 chayotes" {
+					t.Errorf("result = %+v, want the original text", result)
+				}
+
+				if _, err := tr.TranslateToSpanish(context.Background(), "Your groceries are on the way"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("TranslateToSpanish error = %v, want %q", err, tt.wantErr)
+				}
+			})
+		}
+	}
+}
+
+func TestTranslateWithoutKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("OpenAI called without an API key")
+	}))
+	defer server.Close()
+
+	tr := newTestTranslator(t, NewOpenAI, "", server)
+	result, err := tr.TranslateRequest(context.Background(), "3 chayotes")
+	if err == nil {
+		t.Fatal("expected an error without an API key")
+	}
+	if result.CleanedText != "3 chayotes" {
+		t.Errorf("CleanedText = %q, want the original text", result.CleanedText)
+	}
+}
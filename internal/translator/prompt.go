@@ -0,0 +1,118 @@
+package translator
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// defaultPrompt is used when no prompt file is configured or it can't be
+// loaded. Prompt templates get a promptData value.
+const defaultPrompt = `You are translating a grocery request for a mutual aid organization. Extract any private information (address, phone, full last names) and provide a clean translation safe for public posting.
+
+Spanish text:
+{{.Input}}
+
+Respond in JSON format:
+{
+  "budget": "budget if mentioned, or empty string",
+  "items": [
+    {
+      "name": "item in English",
+      "original": "item as written in Spanish",
+      "quantity": "amount as a string, or empty string",
+      "unit": "lbs, pack, can, etc., or empty string",
+      "category": "MEAT, PRODUCE, DAIRY, PANTRY, HOUSEHOLD or OTHER",
+      "notes": "explanation of regional items, or empty string",
+      "uncertain": false
+    }
+  ],
+  "notes": "special instructions in English, or empty string",
+  "address": "extracted address if any, or empty string",
+  "phone": "extracted phone number if any, or empty string"
+}
+
+IMPORTANT:
+- Only include first names in items and notes, remove last names
+- Never put addresses or phone numbers in items or notes
+- Extract them to the address/phone fields
+- Items and notes should be SAFE for public posting
+- Set "uncertain" to true for items you can't make out`
+
+// promptData is what prompt templates can reference
+type promptData struct {
+	Input string // The Spanish request text
+}
+
+// promptTemplate renders the translation prompt from a text/template file,
+// re-reading it whenever its modification time changes so coordinators can
+// improve the glossary without a redeploy. If the file can't be read or
+// parsed, the last good template (or defaultPrompt) keeps being used.
+type promptTemplate struct {
+	path string
+
+	mu      sync.Mutex
+	tmpl    *template.Template
+	modTime time.Time // Of the last version of the file we tried to load
+	missing bool      // Whether we've already logged that the file is missing
+}
+
+func newPromptTemplate(path string) *promptTemplate {
+	p := &promptTemplate{
+		path: path,
+		tmpl: template.Must(template.New("default").Parse(defaultPrompt)),
+	}
+	p.reload()
+	return p
+}
+
+// reload re-parses the prompt file if it changed since it was last loaded
+func (p *promptTemplate) reload() {
+	if p.path == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		if !p.missing {
+			slog.Warn("Prompt file unavailable, using current prompt", "path", p.path, "err", err)
+			p.missing = true
+		}
+		return
+	}
+	p.missing = false
+	if info.ModTime().Equal(p.modTime) {
+		return
+	}
+	p.modTime = info.ModTime()
+
+	tmpl, err := template.ParseFiles(p.path)
+	if err != nil {
+		slog.Error("Prompt file is invalid, using current prompt", "path", p.path, "err", err)
+		return
+	}
+
+	p.tmpl = tmpl
+	slog.Info("Loaded translation prompt", "path", p.path)
+}
+
+func (p *promptTemplate) render(input string) (string, error) {
+	p.reload()
+
+	p.mu.Lock()
+	tmpl := p.tmpl
+	p.mu.Unlock()
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, promptData{Input: input}); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return sb.String(), nil
+}
@@ -22,19 +22,59 @@ TELEGRAM_BOT_TOKEN=your_bot_token_here
 #  curl https://api.telegram.org/bot<TOKEN>/getUpdates)
 VOLUNTEER_CHAT_ID=-1001234567890
 
-# Comma-separated Telegram user IDs for coordinators
+# Comma-separated Telegram user IDs for coordinators. These can't be
+# removed from the bot and are the only ones who can /promote and /demote
+# other coordinators.
 COORDINATOR_IDS=123456789
 
 # Database encryption key (generate with: openssl rand -base64 32)
+# To rotate it, stop the bot and run:
+#   NEW_DB_ENCRYPTION_KEY=<new key> go run ./cmd/rekey
 DB_ENCRYPTION_KEY=your_encryption_key_here
 
 # Paths (for local development)
 DB_PATH=./data/centromex.db
-MODEL_PATH=./models/llama-3.2-3b.Q4_K_M.gguf
+# Translation prompt; edits are picked up without a restart
+PROMPT_PATH=./prompts/translate.txt
+
+# Translation backend: "openai" (needs OPENAI_API_KEY) or "local" for an
+# OpenAI-compatible server on this machine (Ollama, llama.cpp server)
+TRANSLATOR_BACKEND=openai
+OPENAI_API_KEY=
+# TRANSLATOR_URL=http://localhost:11434/v1
+# TRANSLATOR_MODEL=llama3.2:3b
+# Per-call timeout, and retries (with backoff) on rate limits and outages
+TRANSLATOR_TIMEOUT=30s
+TRANSLATOR_MAX_RETRIES=3
+# Tried when the translator above fails. If every translator fails, the
+# original Spanish is posted flagged for a bilingual volunteer.
+# FALLBACK_TRANSLATOR_BACKEND=local
+# FALLBACK_TRANSLATOR_MODEL=gpt-4o
+
+# Set to true to DM each translated card to its coordinator for approval
+# before it is posted to volunteers
+REVIEW_BEFORE_POSTING=false
+
+# Updates from different users are processed in parallel by this many
+# workers. Each user's own messages are always handled in order.
+WORKER_COUNT=4
+
+# Logs never include family messages, addresses, phones or names unless
+# LOG_DEBUG=true. Only turn it on briefly, on a machine you control.
+LOG_DEBUG=false
+# LOG_FORMAT=json
 
 # Leave empty for polling mode (local dev)
 # Set to sprite URL for webhook mode (production)
 WEBHOOK_URL=
+# Required in webhook mode: Telegram sends it with every update and the bot
+# rejects anything without it (generate with: openssl rand -hex 32)
+WEBHOOK_SECRET=
+# Optional: only accept updates from these networks ("telegram" for
+# Telegram's published ranges). Behind a proxy, also set the header it
+# puts the client IP in.
+# WEBHOOK_ALLOWED_IPS=telegram
+# WEBHOOK_CLIENT_IP_HEADER=X-Forwarded-For
 EOF
 
     echo ""
@@ -36,6 +36,11 @@ if [ -z "${DB_ENCRYPTION_KEY:-}" ]; then
     echo "(Save this somewhere safe!)"
 fi
 
+# Webhook mode refuses to start without a secret token for Telegram to send
+if [ -z "${WEBHOOK_SECRET:-}" ]; then
+    WEBHOOK_SECRET=$(openssl rand -hex 32)
+fi
+
 API_URL="https://api.sprites.dev/v1"
 
 # Create sprite if it doesn't exist
@@ -84,8 +89,13 @@ VOLUNTEER_CHAT_ID=$VOLUNTEER_CHAT_ID
 COORDINATOR_IDS=$COORDINATOR_IDS
 DB_ENCRYPTION_KEY=$DB_ENCRYPTION_KEY
 DB_PATH=/data/centromex.db
-MODEL_PATH=/models/llama-3.2-3b.Q4_K_M.gguf
+# To translate with the downloaded model instead of OpenAI, serve it with
+# llama.cpp on port 8081, since the bot's webhook server already uses 8080
+# (llama-server -m /models/llama-3.2-3b.Q4_K_M.gguf --port 8081), and set:
+# TRANSLATOR_BACKEND=local
+# TRANSLATOR_URL=http://localhost:8081/v1
 WEBHOOK_URL=$SPRITE_URL
+WEBHOOK_SECRET=$WEBHOOK_SECRET
 ENVEOF"
 
 echo ""